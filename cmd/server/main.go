@@ -3,9 +3,11 @@ package main
 import (
 	"bufio"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"time"
 
@@ -17,6 +19,7 @@ var (
 	canvasWidth  float64 = 1000
 	canvasHeight float64 = 600
 	simMutex     sync.Mutex
+	world        *sim.World
 )
 
 func listenForEnter() {
@@ -37,9 +40,9 @@ func listenForEnter() {
 
 func restartSimulation() {
 	// Reinitialize the entities or any other necessary state
-	sim.InitializeEntities(entityCount, teamCount, canvasWidth, canvasHeight) // You can change the number of entities as needed
-	sim.InitializeFood(foodCount, canvasWidth, canvasHeight)
-	fmt.Println("Simulation restarted.")
+	world.InitializeEntities(entityCount, teamCount) // You can change the number of entities as needed
+	world.InitializeFood(foodCount)
+	fmt.Println("Simulation restarted with seed", world.Seed())
 }
 
 var (
@@ -57,11 +60,47 @@ var upgrader = websocket.Upgrader{
 var clients = make(map[*websocket.Conn]bool) // Connected clients
 var broadcast = make(chan responseData)      // Broadcast channel for entities
 
+var (
+	broadcastRate = flag.Float64("broadcast-rate", 30, "state broadcasts per second sent to clients")
+	brains        = flag.String("brains", "", "comma separated brain per team, e.g. default,utility:curves.json")
+	flocking      = flag.String("flocking", "", "comma separated flocking per team, e.g. wedge,flock:2/1/1")
+	mapPath       = flag.String("map", "", "JSON file of obstacles to place in the arena")
+	viewCones     = flag.Bool("view-cones", false, "send entity view cones to clients for debugging")
+)
+
 func main() {
-	sim.SetCanvas(canvasWidth, canvasHeight)
-	sim.SetConfig(sim.Config{MinSize: 5, StartMaxSize: 10, MaxSize: 15, BaseSpeed: 10})
-	sim.InitializeEntities(entityCount, teamCount, canvasWidth, canvasHeight)
-	sim.InitializeFood(foodCount, canvasWidth, canvasHeight)
+	flag.Parse()
+
+	var teamFlocking []sim.Flocking
+	if *flocking != "" {
+		var err error
+		if teamFlocking, err = sim.ParseTeamFlocking(*flocking); err != nil {
+			fmt.Println("unable to parse flocking:", err)
+			os.Exit(1)
+		}
+	}
+
+	config := sim.DefaultConfig()
+	config.Flocking = teamFlocking
+	world = sim.NewWorld(config, canvasWidth, canvasHeight)
+	if *brains != "" {
+		if err := world.LoadTeamBrains(strings.Split(*brains, ",")); err != nil {
+			fmt.Println("unable to load brains:", err)
+			os.Exit(1)
+		}
+	}
+	if *mapPath != "" {
+		m, err := sim.LoadMap(*mapPath)
+		if err == nil {
+			err = world.SetObstacles(m.Obstacles)
+		}
+		if err != nil {
+			fmt.Println("unable to load map:", err)
+			os.Exit(1)
+		}
+	}
+	world.InitializeEntities(entityCount, teamCount)
+	world.InitializeFood(foodCount)
 
 	// Serve static files
 	http.Handle("/", http.FileServer(http.Dir("./static")))
@@ -72,6 +111,7 @@ func main() {
 	// Start the simulation update loop in a separate goroutine
 	go listenForEnter()
 	go updateSimulationPeriodically()
+	go broadcastPeriodically()
 	go handleMessages()
 
 	fmt.Println("Server started at http://localhost:8080")
@@ -97,6 +137,16 @@ func handleConnections(w http.ResponseWriter, r *http.Request) {
 	}
 	defer ws.Close()
 
+	// The obstacles never move, so they are sent once before the client
+	// starts receiving state
+	simMutex.Lock()
+	arena := mapData{Obstacles: world.Obstacles()}
+	simMutex.Unlock()
+	if err := ws.WriteJSON(arena); err != nil {
+		fmt.Println("Error sending map to client:", err)
+		return
+	}
+
 	// Register new client
 	clients[ws] = true
 	activeConnections++
@@ -132,13 +182,36 @@ func handleConnections(w http.ResponseWriter, r *http.Request) {
 }
 
 type Settings struct {
-	Population   int
-	TeamCount    int
-	FoodCount    int
-	MinSize      float64
-	StartMaxSize float64
-	MaxSize      float64
-	BaseSpeed    float64
+	Population    int
+	TeamCount     int
+	FoodCount     int
+	MinSize       float64
+	StartMaxSize  float64
+	MaxSize       float64
+	BaseSpeed     float64
+	Seed          int64
+	TickRate      float64
+	FleeRadius    float64
+	ViewAngle     float64
+	ViewDistance  float64
+	MemorySpan    float64
+	Pheromones    bool
+	Communication bool
+	Wrap          bool
+	Collisions    bool
+	Restitution   float64
+	Kinematics    bool
+	Acceleration  float64
+	Drag          float64
+	TurnRate      float64
+	Reproduction  bool
+
+	MaxEnergy        float64
+	BasalMetabolism  float64
+	MovementCost     float64
+	AttackCost       float64
+	FoodEnergy       float64
+	StarvationDamage float64
 }
 
 func settings(message []byte) {
@@ -153,12 +226,37 @@ func settings(message []byte) {
 	entityCount = data.Population
 	foodCount = data.FoodCount
 	simMutex.Lock()
-	sim.SetConfig(sim.Config{
-		MinSize:      data.MinSize,
-		StartMaxSize: data.StartMaxSize,
-		MaxSize:      data.MaxSize,
-		BaseSpeed:    data.BaseSpeed,
-	})
+	// Settings not on the form, such as those set from the command line,
+	// carry over
+	c := world.Config()
+	c.MinSize = data.MinSize
+	c.StartMaxSize = data.StartMaxSize
+	c.MaxSize = data.MaxSize
+	c.BaseSpeed = data.BaseSpeed
+	c.Seed = data.Seed
+	c.TickRate = data.TickRate
+	c.FleeRadius = data.FleeRadius
+	c.ViewAngle = data.ViewAngle
+	c.ViewDistance = data.ViewDistance
+	c.MemorySpan = data.MemorySpan
+	c.Pheromones = data.Pheromones
+	c.Communication = data.Communication
+	c.Wrap = data.Wrap
+	c.Collisions = data.Collisions
+	c.Restitution = data.Restitution
+	c.Kinematics = data.Kinematics
+	c.Acceleration = data.Acceleration
+	c.Drag = data.Drag
+	c.TurnRate = data.TurnRate
+	c.Reproduction = data.Reproduction
+
+	c.MaxEnergy = data.MaxEnergy
+	c.BasalMetabolism = data.BasalMetabolism
+	c.MovementCost = data.MovementCost
+	c.AttackCost = data.AttackCost
+	c.FoodEnergy = data.FoodEnergy
+	c.StarvationDamage = data.StarvationDamage
+	world.SetConfig(c)
 	restartSimulation()
 	simMutex.Unlock()
 
@@ -174,52 +272,98 @@ func resize(message []byte) {
 	fmt.Println("set canvas", data)
 	canvasWidth = float64(data.Width)
 	canvasHeight = float64(data.Height)
-	sim.SetCanvas(canvasWidth, canvasHeight)
 
 	simMutex.Lock()
+	world.SetCanvas(canvasWidth, canvasHeight)
 	// Restart the simulation when Enter is pressed
 	restartSimulation()
 	simMutex.Unlock()
 }
 
 func updateSimulationPeriodically() {
-	ticker := time.NewTicker(16 * time.Millisecond) // Roughly 60 FPS
-	defer ticker.Stop()                             // Ensure the ticker is stopped when the function exits
-	previousTime := time.Now()                      // Track the previous time for deltaTime calculation
+	simMutex.Lock()
+	ticker := time.NewTicker(time.Duration(world.TickDuration() * float64(time.Second)))
+	simMutex.Unlock()
+	defer ticker.Stop()        // Ensure the ticker is stopped when the function exits
+	previousTime := time.Now() // Track the previous time for deltaTime calculation
 
 	for {
-		simMutex.Lock()
 		<-ticker.C
 		currentTime := time.Now()
-		deltaTime := currentTime.Sub(previousTime).Seconds() // Calculate deltaTime in seconds
+		elapsed := currentTime.Sub(previousTime).Seconds() // Wall-clock time since the last wake-up
 		previousTime = currentTime
 
+		simMutex.Lock()
 		// Skip simulation updates if no active connections
+		if activeConnections > 0 {
+			// The world runs however many fixed ticks are due
+			world.Advance(elapsed)
+		}
+		ticker.Reset(time.Duration(world.TickDuration() * float64(time.Second)))
+		simMutex.Unlock()
+	}
+}
+
+func broadcastPeriodically() {
+	ticker := time.NewTicker(time.Duration(float64(time.Second) / *broadcastRate))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		simMutex.Lock()
 		if activeConnections == 0 {
 			simMutex.Unlock()
 			continue
 		}
-
-		// Only update the simulation if there are active connections
-		if activeConnections > 0 {
-			sim.UpdateSimulation(deltaTime) // Pass deltaTime to the UpdateSimulation function
-			entities := sim.GetEntities()   // Get the current state of entities
-			foods := sim.GetFood()
-			// Broadcast the updated entities
-			broadcast <- responseData{
-				Entities:  entities,
-				Foods:     foods,
-				TeamCount: teamCount,
+		// Copy the state so it can be encoded while the simulation moves on
+		data := responseData{
+			Entities:  make([]sim.Entity, 0, len(world.GetEntities())),
+			Foods:     make([]sim.Food, 0, len(world.GetFood())),
+			TeamCount: teamCount,
+		}
+		if food := world.PheromoneHeatmap(sim.FoodTrail, heatmapCellSize); food != nil {
+			data.Heatmaps = map[string]*sim.Heatmap{
+				"food":   food,
+				"danger": world.PheromoneHeatmap(sim.DangerPheromone, heatmapCellSize),
+				"rally":  world.PheromoneHeatmap(sim.RallyPheromone, heatmapCellSize),
 			}
 		}
+		if *viewCones {
+			c := world.Config()
+			data.View = &viewCone{Angle: c.ViewAngle, Distance: c.ViewDistance}
+		}
+		for _, e := range world.GetEntities() {
+			data.Entities = append(data.Entities, *e)
+		}
+		for _, f := range world.GetFood() {
+			data.Foods = append(data.Foods, *f)
+		}
 		simMutex.Unlock()
+
+		broadcast <- data
 	}
 }
 
 type responseData struct {
-	Entities  []*sim.Entity
-	Foods     []*sim.Food
+	Entities  []sim.Entity
+	Foods     []sim.Food
 	TeamCount int
+	View      *viewCone               `json:",omitempty"`
+	Heatmaps  map[string]*sim.Heatmap `json:",omitempty"` // Pheromones by kind
+}
+
+// mapData is sent to each client once when it connects.
+type mapData struct {
+	Obstacles []sim.Obstacle
+}
+
+// heatmapCellSize is the resolution pheromones are sent to clients at.
+const heatmapCellSize = 20
+
+// viewCone describes what entities can see, for drawing around each one.
+// Zero values mean all round and unlimited, as in sim.Config.
+type viewCone struct {
+	Angle    float64
+	Distance float64
 }
 
 func handleMessages() {
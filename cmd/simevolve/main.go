@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/lukegriffith/simulation/internal/evolution"
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+func main() {
+	var (
+		population    = flag.Int("population", 40, "entities per generation, split across teams")
+		teamCount     = flag.Int("teams", 2, "number of teams, each with its own gene pool")
+		foodCount     = flag.Int("food", 200, "number of food items")
+		width         = flag.Float64("width", 1000, "arena width")
+		height        = flag.Float64("height", 600, "arena height")
+		minSize       = flag.Float64("min-size", 5, "minimum birth size")
+		startMaxSize  = flag.Float64("start-max-size", 10, "maximum birth size of the first generation")
+		maxSize       = flag.Float64("max-size", 15, "size entities stop growing at")
+		baseSpeed     = flag.Float64("base-speed", 10, "speed of a zero sized entity")
+		seed          = flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+		tickRate      = flag.Float64("tick-rate", 60, "simulation ticks per simulated second")
+		generations   = flag.Int("generations", 50, "generations to evolve")
+		ticks         = flag.Int("ticks", 60*60, "tick budget per generation, 0 runs until one team remains")
+		mutationRate  = flag.Float64("mutation-rate", 0.2, "chance each trait or weight of a child mutates")
+		mutationScale = flag.Float64("mutation-scale", 0.1, "mutation size as a fraction of a trait's range, or a weight's standard deviation with -neural")
+		csvPath       = flag.String("csv", "", "write per generation statistics to this CSV file")
+		genomesPath   = flag.String("out", "", "write the final gene pools, or networks with -neural, to this JSON file")
+		neural        = flag.Bool("neural", false, "evolve neural network brains instead of genomes")
+		hidden        = flag.String("hidden", "8", "comma separated hidden layer sizes for -neural")
+		elite         = flag.Int("elite", 2, "best networks kept unchanged each generation with -neural")
+	)
+	flag.Parse()
+
+	simConfig := sim.DefaultConfig()
+	simConfig.MinSize = *minSize
+	simConfig.StartMaxSize = *startMaxSize
+	simConfig.MaxSize = *maxSize
+	simConfig.BaseSpeed = *baseSpeed
+	simConfig.Seed = *seed
+	simConfig.TickRate = *tickRate
+
+	if *neural {
+		var layers []int
+		for _, size := range strings.Split(*hidden, ",") {
+			if size == "" {
+				continue
+			}
+			n, err := strconv.Atoi(size)
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "bad hidden layer size %q\n", size)
+				os.Exit(2)
+			}
+			layers = append(layers, n)
+		}
+
+		fmt.Printf("%-4s %8s %8s\n", "gen", "mean", "max")
+		best, err := evolution.RunNeural(evolution.NeuralConfig{
+			Sim:           simConfig,
+			Width:         *width,
+			Height:        *height,
+			Population:    *population,
+			Teams:         *teamCount,
+			Food:          *foodCount,
+			Generations:   *generations,
+			Ticks:         *ticks,
+			Hidden:        layers,
+			Elite:         *elite,
+			MutationRate:  *mutationRate,
+			MutationScale: *mutationScale,
+		}, func(g evolution.NeuralGeneration) {
+			fmt.Printf("%-4d %8.1f %8.1f\n", g.Index, g.MeanFitness, g.MaxFitness)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		if *genomesPath != "" {
+			if err := sim.SaveNetworks(*genomesPath, best); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+		}
+		return
+	}
+
+	var records *csv.Writer
+	if *csvPath != "" {
+		f, err := os.Create(*csvPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		defer f.Close()
+		records = csv.NewWriter(f)
+		defer records.Flush()
+		records.Write([]string{"generation", "seed", "ticks", "team", "survivors", "mean_fitness", "max_fitness",
+			"speed", "perception", "hunger", "aggression", "assist", "birth_size"})
+	}
+
+	fmt.Printf("%-4s %-4s %9s %8s %8s %6s %6s %6s %6s %6s %6s\n",
+		"gen", "team", "survivors", "mean", "max", "speed", "sense", "hunger", "aggr", "assist", "size")
+	final, err := evolution.Run(evolution.Config{
+		Sim:           simConfig,
+		Width:         *width,
+		Height:        *height,
+		Population:    *population,
+		Teams:         *teamCount,
+		Food:          *foodCount,
+		Generations:   *generations,
+		Ticks:         *ticks,
+		MutationRate:  *mutationRate,
+		MutationScale: *mutationScale,
+	}, func(g evolution.Generation) {
+		for _, t := range g.Teams {
+			m := t.MeanGenome
+			fmt.Printf("%-4d %-4d %9d %8.1f %8.1f %6.2f %6.1f %6.1f %6.2f %6.2f %6.1f\n",
+				g.Index, t.Team, t.Survivors, t.MeanFitness, t.MaxFitness,
+				m.SpeedMultiplier, m.PerceptionRadius, m.HungerThreshold, m.Aggression, m.AssistPropensity, m.BirthSize)
+			if records != nil {
+				records.Write([]string{
+					strconv.Itoa(g.Index), strconv.FormatInt(g.Seed, 10), strconv.Itoa(g.Ticks), strconv.Itoa(t.Team),
+					strconv.Itoa(t.Survivors), ftoa(t.MeanFitness), ftoa(t.MaxFitness),
+					ftoa(m.SpeedMultiplier), ftoa(m.PerceptionRadius), ftoa(m.HungerThreshold),
+					ftoa(m.Aggression), ftoa(m.AssistPropensity), ftoa(m.BirthSize),
+				})
+			}
+		}
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	if *genomesPath != "" {
+		data, err := json.MarshalIndent(final, "", "  ")
+		if err == nil {
+			err = os.WriteFile(*genomesPath, data, 0o644)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func ftoa(f float64) string {
+	return strconv.FormatFloat(f, 'f', 4, 64)
+}
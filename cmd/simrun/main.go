@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+func main() {
+	var (
+		population   = flag.Int("population", 10, "number of entities")
+		teamCount    = flag.Int("teams", 2, "number of teams")
+		foodCount    = flag.Int("food", 200, "number of food items")
+		width        = flag.Float64("width", 1000, "arena width")
+		height       = flag.Float64("height", 600, "arena height")
+		minSize      = flag.Float64("min-size", 5, "minimum starting entity size")
+		startMaxSize = flag.Float64("start-max-size", 10, "maximum starting entity size")
+		maxSize      = flag.Float64("max-size", 15, "size entities stop growing at")
+		baseSpeed    = flag.Float64("base-speed", 10, "speed of a zero sized entity")
+		seed         = flag.Int64("seed", 0, "random seed, 0 picks one from the clock")
+		tickRate     = flag.Float64("tick-rate", 60, "simulation ticks per simulated second")
+		fleeRadius   = flag.Float64("flee-radius", 100, "distance larger enemies are fled from")
+		viewAngle    = flag.Float64("view-angle", 0, "degrees entities see across around their heading, 0 sees all round")
+		viewDistance = flag.Float64("view-distance", 0, "distance entities see, 0 is unlimited")
+		memorySpan   = flag.Float64("memory-span", 0, "seconds entities remember what they saw, 0 for no memory")
+		pheromones   = flag.Bool("pheromones", false, "let teams lay and follow scent trails")
+		diffusion    = flag.Float64("pheromone-diffusion", 2, "rate scents spread to neighbouring cells per second")
+		evaporation  = flag.Float64("pheromone-evaporation", 0.2, "fraction of scent lost per second")
+		comms        = flag.Bool("communication", false, "let teammates send each other messages")
+		msgRange     = flag.Float64("message-range", 300, "distance messages reach from their sender")
+		msgLatency   = flag.Float64("message-latency", 0.25, "seconds before a message arrives")
+		msgInterval  = flag.Float64("message-interval", 1, "seconds between messages from one entity")
+		reproduction = flag.Bool("reproduction", false, "let healthy entities give birth")
+		birthHealth  = flag.Float64("birth-health", 150, "health an entity needs to give birth")
+		birthCost    = flag.Float64("birth-cost", 75, "health a birth moves from parent to offspring")
+		birthWait    = flag.Float64("birth-cooldown", 10, "seconds between births")
+		mutRate      = flag.Float64("mutation-rate", 0.2, "chance each trait of an offspring mutates")
+		mutScale     = flag.Float64("mutation-scale", 0.05, "offspring mutation size as a fraction of the trait's range")
+		maxEnergy    = flag.Float64("max-energy", 100, "most energy an entity can store")
+		basal        = flag.Float64("basal-metabolism", 0.05, "energy burned per unit of width per second")
+		moveCost     = flag.Float64("movement-cost", 0.02, "energy burned per unit of speed per second")
+		attackCost   = flag.Float64("attack-cost", 2, "energy burned per attack")
+		foodEnergy   = flag.Float64("food-energy", 5, "energy restored per unit of food size")
+		starvation   = flag.Float64("starvation-damage", 5, "health lost per second without energy")
+		ticks        = flag.Int("ticks", 60*60*10, "tick budget, 0 runs until one team remains however long that takes")
+		brains       = flag.String("brains", "", "comma separated brain per team, e.g. default,utility:curves.json")
+		flocking     = flag.String("flocking", "", "comma separated flocking per team, e.g. wedge,flock:2/1/1")
+		pathBudget   = flag.Int("path-budget", 50, "path searches per tick across all entities")
+		wrap         = flag.Bool("wrap", false, "wrap the arena's edges round so there are no walls")
+		collisions   = flag.Bool("collisions", false, "make entities solid so they push each other apart")
+		restitution  = flag.Float64("restitution", 0, "bounciness of collisions, from 0 to 1")
+		kinematics   = flag.Bool("kinematics", false, "steer by acceleration with drag and a turn rate")
+		acceleration = flag.Float64("acceleration", 20, "acceleration of a size 10 entity at full effort")
+		drag         = flag.Float64("drag", 0.5, "fraction of velocity lost per second")
+		turnRate     = flag.Float64("turn-rate", 360, "degrees per second entities can turn")
+		mapPath      = flag.String("map", "", "JSON file of obstacles to place in the arena")
+		verbose      = flag.Bool("v", false, "print the simulation event log")
+	)
+	flag.Parse()
+
+	if *teamCount < 1 || *population < 0 || *foodCount < 0 {
+		fmt.Fprintln(os.Stderr, "teams must be at least 1 and counts must not be negative")
+		os.Exit(2)
+	}
+	if *ticks <= 0 && *teamCount == 1 {
+		fmt.Fprintln(os.Stderr, "a single team run needs a -ticks budget")
+		os.Exit(2)
+	}
+
+	var teamFlocking []sim.Flocking
+	if *flocking != "" {
+		var err error
+		if teamFlocking, err = sim.ParseTeamFlocking(*flocking); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+	}
+
+	world := sim.NewWorld(sim.Config{
+		MinSize:      *minSize,
+		StartMaxSize: *startMaxSize,
+		MaxSize:      *maxSize,
+		BaseSpeed:    *baseSpeed,
+		Seed:         *seed,
+		TickRate:     *tickRate,
+		FleeRadius:   *fleeRadius,
+		ViewAngle:    *viewAngle,
+		ViewDistance: *viewDistance,
+		MemorySpan:   *memorySpan,
+
+		Pheromones:           *pheromones,
+		PheromoneDiffusion:   *diffusion,
+		PheromoneEvaporation: *evaporation,
+
+		Communication:   *comms,
+		MessageRange:    *msgRange,
+		MessageLatency:  *msgLatency,
+		MessageInterval: *msgInterval,
+
+		Flocking:   teamFlocking,
+		PathBudget: *pathBudget,
+
+		Wrap:        *wrap,
+		Collisions:  *collisions,
+		Restitution: *restitution,
+
+		Kinematics:   *kinematics,
+		Acceleration: *acceleration,
+		Drag:         *drag,
+		TurnRate:     *turnRate,
+
+		Reproduction:         *reproduction,
+		ReproductionHealth:   *birthHealth,
+		ReproductionCost:     *birthCost,
+		ReproductionCooldown: *birthWait,
+		MutationRate:         *mutRate,
+		MutationScale:        *mutScale,
+
+		MaxEnergy:        *maxEnergy,
+		BasalMetabolism:  *basal,
+		MovementCost:     *moveCost,
+		AttackCost:       *attackCost,
+		FoodEnergy:       *foodEnergy,
+		StarvationDamage: *starvation,
+	}, *width, *height)
+	if !*verbose {
+		world.SetLogOutput(nil)
+	}
+	if *brains != "" {
+		if err := world.LoadTeamBrains(strings.Split(*brains, ",")); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *mapPath != "" {
+		m, err := sim.LoadMap(*mapPath)
+		if err == nil {
+			err = world.SetObstacles(m.Obstacles)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	world.InitializeEntities(*population, *teamCount)
+	world.InitializeFood(*foodCount)
+
+	printSummary(world.Run(*ticks), world.TickDuration())
+}
+
+func printSummary(s sim.Summary, tickDuration float64) {
+	fmt.Printf("seed:    %d\n", s.Seed)
+	fmt.Printf("ticks:   %d (%.2fs simulated)\n", s.Ticks, float64(s.Ticks)*tickDuration)
+	if s.Winner >= 0 {
+		fmt.Printf("winner:  team %d\n", s.Winner)
+	} else {
+		fmt.Println("winner:  none")
+	}
+	fmt.Println()
+	fmt.Printf("%-6s %10s %10s %10s %10s %12s\n", "team", "survivors", "food", "kills", "births", "survival")
+	for team := range s.Survivors {
+		fmt.Printf("%-6d %10d %10d %10d %10d %11.1fs\n", team, s.Survivors[team], s.FoodEaten[team], s.Kills[team], s.Births[team], s.MeanSurvivalTicks[team]*tickDuration)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/lukegriffith/simulation/internal/experiment"
+)
+
+func main() {
+	var (
+		configPath = flag.String("config", "", "JSON sweep definition, defaults to a single combination of server defaults")
+		workers    = flag.Int("workers", 0, "trials run in parallel, 0 uses every CPU")
+		trials     = flag.Int("trials", 0, "override trials per combination")
+	)
+	flag.Parse()
+
+	sweep := experiment.DefaultSweep()
+	if *configPath != "" {
+		var err error
+		sweep, err = experiment.LoadSweep(*configPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *trials > 0 {
+		sweep.Trials = *trials
+	}
+	if err := sweep.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	combos := sweep.Combinations()
+	fmt.Printf("running %d combinations x %d trials\n\n", len(combos), sweep.Trials)
+
+	results, err := experiment.Run(sweep, *workers)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, r := range results {
+		fmt.Printf("%s (%d trials)\n", r.Params, r.Trials)
+		fmt.Printf("  duration %8.1fs [%.1f, %.1f]\n", r.Duration.Mean, r.Duration.Low, r.Duration.High)
+		for team := range r.WinRate {
+			win, survival := r.WinRate[team], r.Survival[team]
+			fmt.Printf("  team %-3d wins %5.1f%% [%5.1f%%, %5.1f%%]  survival %7.1fs [%.1f, %.1f]\n",
+				team, 100*win.Mean, 100*win.Low, 100*win.High, survival.Mean, survival.Low, survival.High)
+		}
+		fmt.Printf("  draws    %5.1f%% [%5.1f%%, %5.1f%%]\n\n", 100*r.DrawRate.Mean, 100*r.DrawRate.Low, 100*r.DrawRate.High)
+	}
+}
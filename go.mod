@@ -2,4 +2,4 @@ module github.com/lukegriffith/simulation
 
 go 1.22.6
 
-require github.com/gorilla/websocket v1.5.3 // indirect
+require github.com/gorilla/websocket v1.5.3
@@ -0,0 +1,184 @@
+package evolution
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+// Config describes an evolution run. Each team evolves its own gene pool.
+type Config struct {
+	Sim           sim.Config
+	Width, Height float64
+	Population    int // Entities per generation, split across teams
+	Teams         int
+	Food          int
+	Generations   int
+	Ticks         int // Tick budget per generation, 0 runs until one team remains
+
+	MutationRate  float64 // Chance each trait of a child mutates
+	MutationScale float64 // Mutation size as a fraction of the trait's range
+}
+
+// TeamStats summarises one team's generation.
+type TeamStats struct {
+	Team        int
+	Survivors   int
+	MeanFitness float64
+	MaxFitness  float64
+	MeanGenome  sim.Genome
+}
+
+// Generation summarises one generation of every team.
+type Generation struct {
+	Index int
+	Seed  int64
+	Ticks int
+	Teams []TeamStats
+}
+
+type scored struct {
+	genome  sim.Genome
+	fitness float64
+	alive   bool
+}
+
+// Run evolves c.Generations generations and calls report after each. It
+// returns the genomes of the generation that would come next, indexed by
+// team. Generation g runs with seed c.Sim.Seed+g, so a run is reproducible
+// from its seed.
+func Run(c Config, report func(Generation)) ([][]sim.Genome, error) {
+	if err := validate(c.Population, c.Teams, c.Ticks); err != nil {
+		return nil, err
+	}
+	if c.Sim.Seed == 0 {
+		c.Sim.Seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(c.Sim.Seed))
+	world := sim.NewWorld(c.Sim, c.Width, c.Height)
+	world.SetLogOutput(nil)
+
+	// Generation zero spreads out from the default traits
+	genomes := make([][]sim.Genome, c.Teams)
+	for i := 0; i < c.Population; i++ {
+		team := i % c.Teams
+		genomes[team] = append(genomes[team], sim.Mutate(world.DefaultGenome(), c.Sim, 1, 0.25, rng))
+	}
+
+	for gen := 0; gen < c.Generations; gen++ {
+		cfg := c.Sim
+		cfg.Seed = c.Sim.Seed + int64(gen)
+		world.SetConfig(cfg)
+		world.InitializeEntitiesFromGenomes(genomes)
+		world.InitializeFood(c.Food)
+		summary := world.Run(c.Ticks)
+
+		results := make([][]scored, c.Teams)
+		for _, e := range world.GetEntities() {
+			results[e.TeamID] = append(results[e.TeamID], scored{genome: e.Genome, fitness: e.Fitness(), alive: e.Active})
+		}
+
+		g := Generation{Index: gen, Seed: cfg.Seed, Ticks: summary.Ticks}
+		for team, r := range results {
+			g.Teams = append(g.Teams, teamStats(team, r))
+			genomes[team] = breed(r, len(genomes[team]), c, rng)
+		}
+		if report != nil {
+			report(g)
+		}
+	}
+	return genomes, nil
+}
+
+// validate checks a run can go ahead: it has at least one team, an entity
+// on every team to breed from, and a tick budget to end generations with
+// a single team.
+func validate(population, teams, ticks int) error {
+	if teams < 1 {
+		return fmt.Errorf("teams must be at least 1, got %d", teams)
+	}
+	if population < teams {
+		return fmt.Errorf("population must be at least one per team, got %d for %d teams", population, teams)
+	}
+	if teams == 1 && ticks <= 0 {
+		return fmt.Errorf("single team runs need a tick budget")
+	}
+	return nil
+}
+
+func teamStats(team int, r []scored) TeamStats {
+	ts := TeamStats{Team: team}
+	if len(r) == 0 {
+		return ts
+	}
+	var sum float64
+	var g sim.Genome
+	for i, s := range r {
+		if s.alive {
+			ts.Survivors++
+		}
+		sum += s.fitness
+		if i == 0 || s.fitness > ts.MaxFitness {
+			ts.MaxFitness = s.fitness
+		}
+		g.SpeedMultiplier += s.genome.SpeedMultiplier
+		g.PerceptionRadius += s.genome.PerceptionRadius
+		g.HungerThreshold += s.genome.HungerThreshold
+		g.Aggression += s.genome.Aggression
+		g.AssistPropensity += s.genome.AssistPropensity
+		g.BirthSize += s.genome.BirthSize
+	}
+	n := float64(len(r))
+	ts.MeanFitness = sum / n
+	ts.MeanGenome = sim.Genome{
+		SpeedMultiplier:  g.SpeedMultiplier / n,
+		PerceptionRadius: g.PerceptionRadius / n,
+		HungerThreshold:  g.HungerThreshold / n,
+		Aggression:       g.Aggression / n,
+		AssistPropensity: g.AssistPropensity / n,
+		BirthSize:        g.BirthSize / n,
+	}
+	return ts
+}
+
+// breed selects the survivors of a generation as parents, topping up with
+// the fittest of the dead if fewer than two survived, and fills the next
+// generation with their mutated offspring.
+func breed(r []scored, size int, c Config, rng *rand.Rand) []sim.Genome {
+	if len(r) == 0 {
+		return nil
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		if r[i].alive != r[j].alive {
+			return r[i].alive
+		}
+		return r[i].fitness > r[j].fitness
+	})
+	parents := 0
+	for parents < len(r) && r[parents].alive {
+		parents++
+	}
+	if parents < 2 {
+		parents = min(2, len(r))
+	}
+	pool := r[:parents]
+
+	next := make([]sim.Genome, size)
+	for i := range next {
+		a, b := tournament(pool, rng), tournament(pool, rng)
+		next[i] = sim.Mutate(sim.Crossover(a.genome, b.genome, rng), c.Sim, c.MutationRate, c.MutationScale, rng)
+	}
+	return next
+}
+
+// tournament picks the fitter of two random parents.
+func tournament(pool []scored, rng *rand.Rand) scored {
+	a, b := pool[rng.Intn(len(pool))], pool[rng.Intn(len(pool))]
+	if b.fitness > a.fitness {
+		return b
+	}
+	return a
+}
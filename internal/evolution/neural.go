@@ -0,0 +1,139 @@
+package evolution
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+// NeuralConfig describes a neuroevolution run. Every entity in a
+// generation is driven by its own network from a single pool shared by all
+// teams, so networks are scored against each other.
+type NeuralConfig struct {
+	Sim           sim.Config
+	Width, Height float64
+	Population    int // Networks per generation
+	Teams         int
+	Food          int
+	Generations   int
+	Ticks         int   // Tick budget per generation, 0 runs until one team remains
+	Hidden        []int // Hidden layer sizes
+
+	Elite         int     // Best networks copied unchanged into the next generation
+	MutationRate  float64 // Chance each weight of a child mutates
+	MutationScale float64 // Standard deviation of a weight mutation
+}
+
+// NeuralGeneration summarises one generation of networks.
+type NeuralGeneration struct {
+	Index       int
+	Seed        int64
+	Ticks       int
+	MeanFitness float64
+	MaxFitness  float64
+}
+
+type scoredNetwork struct {
+	net     *sim.Network
+	fitness float64
+}
+
+// RunNeural evolves networks by weight mutation and crossover, scoring each
+// by the fitness of the entity it drove. It calls report after each
+// generation and returns the last generation's networks, best first.
+func RunNeural(c NeuralConfig, report func(NeuralGeneration)) ([]*sim.Network, error) {
+	if err := validate(c.Population, c.Teams, c.Ticks); err != nil {
+		return nil, err
+	}
+	for _, size := range c.Hidden {
+		if size < 1 {
+			return nil, fmt.Errorf("hidden layers need at least one neuron, got %d", size)
+		}
+	}
+	if c.Sim.Seed == 0 {
+		c.Sim.Seed = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(c.Sim.Seed))
+	world := sim.NewWorld(c.Sim, c.Width, c.Height)
+	world.SetLogOutput(nil)
+
+	layers := append(append([]int{sim.NeuralInputs}, c.Hidden...), sim.NeuralOutputs)
+	networks := make([]*sim.Network, c.Population)
+	for i := range networks {
+		networks[i] = sim.NewNetwork(layers, rng)
+	}
+
+	var ranked []scoredNetwork
+	for gen := 0; gen < c.Generations; gen++ {
+		cfg := c.Sim
+		cfg.Seed = c.Sim.Seed + int64(gen)
+		world.SetConfig(cfg)
+		world.InitializeEntities(len(networks), c.Teams)
+		world.InitializeFood(c.Food)
+		for i, e := range world.GetEntities() {
+			e.SetBrain(sim.NewNeuralBrain(networks[i]))
+		}
+		summary := world.Run(c.Ticks)
+
+		ranked = ranked[:0]
+		total := 0.0
+		for i, e := range world.GetEntities()[:len(networks)] {
+			ranked = append(ranked, scoredNetwork{net: networks[i], fitness: e.Fitness()})
+			total += e.Fitness()
+		}
+		sort.SliceStable(ranked, func(i, j int) bool { return ranked[i].fitness > ranked[j].fitness })
+
+		if report != nil {
+			report(NeuralGeneration{
+				Index:       gen,
+				Seed:        cfg.Seed,
+				Ticks:       summary.Ticks,
+				MeanFitness: total / float64(len(ranked)),
+				MaxFitness:  ranked[0].fitness,
+			})
+		}
+		if gen < c.Generations-1 {
+			networks = nextNetworks(ranked, c, rng)
+		}
+	}
+
+	best := make([]*sim.Network, len(ranked))
+	for i, r := range ranked {
+		best[i] = r.net
+	}
+	return best, nil
+}
+
+// nextNetworks keeps the elite and fills the rest of the generation with
+// mutated crossovers of tournament-selected parents.
+func nextNetworks(ranked []scoredNetwork, c NeuralConfig, rng *rand.Rand) []*sim.Network {
+	next := make([]*sim.Network, 0, len(ranked))
+	for i := 0; i < c.Elite && i < len(ranked); i++ {
+		next = append(next, ranked[i].net)
+	}
+	for len(next) < len(ranked) {
+		a, b := tournamentNetwork(ranked, rng), tournamentNetwork(ranked, rng)
+		child := a.Clone()
+		for i := range child.Weights {
+			if rng.Intn(2) == 1 {
+				child.Weights[i] = b.Weights[i]
+			}
+			if rng.Float64() < c.MutationRate {
+				child.Weights[i] += rng.NormFloat64() * c.MutationScale
+			}
+		}
+		next = append(next, child)
+	}
+	return next
+}
+
+func tournamentNetwork(ranked []scoredNetwork, rng *rand.Rand) *sim.Network {
+	a, b := ranked[rng.Intn(len(ranked))], ranked[rng.Intn(len(ranked))]
+	if b.fitness > a.fitness {
+		return b.net
+	}
+	return a.net
+}
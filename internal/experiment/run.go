@@ -0,0 +1,98 @@
+package experiment
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+type trial struct {
+	combo int
+	seed  int64
+}
+
+type trialResult struct {
+	combo   int
+	summary sim.Summary
+}
+
+// Run executes every trial of the sweep across workers goroutines and
+// returns one aggregated result per combination, in Combinations order.
+// A workers value of zero or less uses every CPU.
+func Run(s Sweep, workers int) ([]Result, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	combos := s.Combinations()
+
+	// Brains are safe to share, so each is loaded once for every trial
+	brains := make([]sim.Brain, len(s.Brains))
+	for team, spec := range s.Brains {
+		b, err := sim.LoadBrain(spec)
+		if err != nil {
+			return nil, fmt.Errorf("team %d: %w", team, err)
+		}
+		brains[team] = b
+	}
+
+	trials := make(chan trial)
+	results := make(chan trialResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for t := range trials {
+				results <- trialResult{combo: t.combo, summary: runTrial(s, combos[t.combo], brains, t.seed)}
+			}
+		}()
+	}
+
+	go func() {
+		for c := range combos {
+			for i := 0; i < s.Trials; i++ {
+				trials <- trial{combo: c, seed: s.Seed + int64(i)}
+			}
+		}
+		close(trials)
+		wg.Wait()
+		close(results)
+	}()
+
+	// Collect per combination, then sort by seed so aggregation does not
+	// depend on which worker finished first
+	summaries := make([][]sim.Summary, len(combos))
+	for r := range results {
+		summaries[r.combo] = append(summaries[r.combo], r.summary)
+	}
+
+	aggregated := make([]Result, len(combos))
+	for c, params := range combos {
+		aggregated[c] = aggregate(params, s.TickRate, summaries[c])
+	}
+	return aggregated, nil
+}
+
+func runTrial(s Sweep, p Params, brains []sim.Brain, seed int64) sim.Summary {
+	c := sim.DefaultConfig()
+	c.MinSize = p.MinSize
+	c.StartMaxSize = p.StartMaxSize
+	c.MaxSize = p.MaxSize
+	c.BaseSpeed = p.Speed
+	c.Seed = seed
+	c.TickRate = s.TickRate
+	world := sim.NewWorld(c, s.Width, s.Height)
+	world.SetLogOutput(nil)
+	for team, b := range brains {
+		world.SetTeamBrain(team, b)
+	}
+	world.InitializeEntities(p.Population, p.Teams)
+	world.InitializeFood(p.Food)
+	return world.Run(s.Ticks)
+}
@@ -0,0 +1,102 @@
+package experiment
+
+import (
+	"math"
+	"sort"
+
+	"github.com/lukegriffith/simulation/internal/sim"
+)
+
+// z score for a 95% confidence interval
+const z95 = 1.959964
+
+// Interval is an estimate with a 95% confidence interval.
+type Interval struct {
+	Mean, Low, High float64
+}
+
+// meanInterval estimates the mean of samples with a normal approximation.
+func meanInterval(samples []float64) Interval {
+	n := float64(len(samples))
+	if n == 0 {
+		return Interval{}
+	}
+	var sum float64
+	for _, v := range samples {
+		sum += v
+	}
+	mean := sum / n
+	if n < 2 {
+		return Interval{Mean: mean, Low: mean, High: mean}
+	}
+	var sq float64
+	for _, v := range samples {
+		sq += (v - mean) * (v - mean)
+	}
+	margin := z95 * math.Sqrt(sq/(n-1)) / math.Sqrt(n)
+	return Interval{Mean: mean, Low: mean - margin, High: mean + margin}
+}
+
+// wilsonInterval estimates a proportion from successes out of n trials. It
+// behaves far better than the normal approximation near 0 and 1, which win
+// rates of lopsided matchups often are.
+func wilsonInterval(successes, n int) Interval {
+	if n == 0 {
+		return Interval{}
+	}
+	p := float64(successes) / float64(n)
+	nf := float64(n)
+	denom := 1 + z95*z95/nf
+	centre := (p + z95*z95/(2*nf)) / denom
+	margin := z95 * math.Sqrt(p*(1-p)/nf+z95*z95/(4*nf*nf)) / denom
+	return Interval{Mean: p, Low: centre - margin, High: centre + margin}
+}
+
+// Result aggregates the trials of one parameter combination.
+type Result struct {
+	Params Params
+	Trials int
+
+	WinRate  []Interval // Per team
+	DrawRate Interval   // No single team left when the trial ended
+	Survival []Interval // Mean entity survival per team, in seconds
+	Duration Interval   // Trial length in seconds
+}
+
+func aggregate(p Params, tickRate float64, summaries []sim.Summary) Result {
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Seed < summaries[j].Seed })
+	if tickRate <= 0 {
+		tickRate = 60
+	}
+
+	r := Result{
+		Params:   p,
+		Trials:   len(summaries),
+		WinRate:  make([]Interval, p.Teams),
+		Survival: make([]Interval, p.Teams),
+	}
+
+	wins := make([]int, p.Teams)
+	draws := 0
+	survival := make([][]float64, p.Teams)
+	var duration []float64
+	for _, s := range summaries {
+		if s.Winner >= 0 {
+			wins[s.Winner]++
+		} else {
+			draws++
+		}
+		for team, ticks := range s.MeanSurvivalTicks {
+			survival[team] = append(survival[team], ticks/tickRate)
+		}
+		duration = append(duration, float64(s.Ticks)/tickRate)
+	}
+
+	for team := 0; team < p.Teams; team++ {
+		r.WinRate[team] = wilsonInterval(wins[team], len(summaries))
+		r.Survival[team] = meanInterval(survival[team])
+	}
+	r.DrawRate = wilsonInterval(draws, len(summaries))
+	r.Duration = meanInterval(duration)
+	return r
+}
@@ -0,0 +1,155 @@
+package experiment
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Range describes the values a parameter takes in a sweep. A zero Step
+// sweeps only Min.
+type Range struct {
+	Min, Max, Step float64
+}
+
+// Fixed returns a range holding a single value.
+func Fixed(v float64) Range {
+	return Range{Min: v, Max: v}
+}
+
+// Values expands the range into the values it covers, Min and Max included.
+func (r Range) Values() []float64 {
+	if r.Step <= 0 || r.Max <= r.Min {
+		return []float64{r.Min}
+	}
+	var values []float64
+	steps := int(math.Floor((r.Max-r.Min)/r.Step + 1e-9))
+	for i := 0; i <= steps; i++ {
+		values = append(values, r.Min+float64(i)*r.Step)
+	}
+	return values
+}
+
+// Sweep defines a grid of parameter combinations and how many seeded
+// trials to run for each.
+type Sweep struct {
+	Population   Range
+	Teams        Range
+	Food         Range
+	MinSize      Range
+	StartMaxSize Range
+	MaxSize      Range
+	BaseSpeed    Range
+
+	Width, Height float64
+	TickRate      float64
+	Ticks         int   // Tick budget per trial, 0 runs until one team remains
+	Trials        int   // Trials per combination
+	Seed          int64 // Trial i of every combination uses Seed+i
+	// Brains holds a sim.LoadBrain spec per team. Teams without one use
+	// the default brain.
+	Brains []string
+}
+
+// DefaultSweep returns a sweep of a single combination matching the
+// server's default settings.
+func DefaultSweep() Sweep {
+	return Sweep{
+		Population:   Fixed(10),
+		Teams:        Fixed(2),
+		Food:         Fixed(200),
+		MinSize:      Fixed(5),
+		StartMaxSize: Fixed(10),
+		MaxSize:      Fixed(15),
+		BaseSpeed:    Fixed(10),
+		Width:        1000,
+		Height:       600,
+		TickRate:     60,
+		Ticks:        60 * 60 * 10,
+		Trials:       20,
+		Seed:         1,
+	}
+}
+
+// LoadSweep reads a sweep definition from a JSON file. Fields missing from
+// the file keep their DefaultSweep values.
+func LoadSweep(path string) (Sweep, error) {
+	s := DefaultSweep()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return s, err
+	}
+	if err := json.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("parsing sweep %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Validate checks the sweep can be run and reproduced: it has trials and
+// an arena, no trial's seed is zero, which would pick one from the clock,
+// every combination has at least one team and no negative counts, and
+// single team combinations have a tick budget to end them.
+func (s Sweep) Validate() error {
+	if s.Trials < 1 {
+		return fmt.Errorf("trials must be at least 1, got %d", s.Trials)
+	}
+	if s.Width <= 0 || s.Height <= 0 {
+		return fmt.Errorf("width and height must be positive, got %g and %g", s.Width, s.Height)
+	}
+	if s.Seed <= 0 && s.Seed+int64(s.Trials) > 0 {
+		return fmt.Errorf("seeds %d to %d include 0, which is not reproducible", s.Seed, s.Seed+int64(s.Trials)-1)
+	}
+	for _, p := range s.Combinations() {
+		if p.Teams < 1 {
+			return fmt.Errorf("teams must be at least 1, got %d", p.Teams)
+		}
+		if p.Population < 0 || p.Food < 0 {
+			return fmt.Errorf("population and food must not be negative, got %d and %d", p.Population, p.Food)
+		}
+		if p.Teams == 1 && s.Ticks <= 0 {
+			return fmt.Errorf("single team combinations need a Ticks budget")
+		}
+	}
+	return nil
+}
+
+// Params is one combination of swept parameters.
+type Params struct {
+	Population, Teams, Food               int
+	MinSize, StartMaxSize, MaxSize, Speed float64
+}
+
+func (p Params) String() string {
+	return fmt.Sprintf("population=%d teams=%d food=%d min=%g startmax=%g max=%g speed=%g",
+		p.Population, p.Teams, p.Food, p.MinSize, p.StartMaxSize, p.MaxSize, p.Speed)
+}
+
+// Combinations expands the sweep into every parameter combination.
+func (s Sweep) Combinations() []Params {
+	var combos []Params
+	for _, population := range s.Population.Values() {
+		for _, teams := range s.Teams.Values() {
+			for _, food := range s.Food.Values() {
+				for _, minSize := range s.MinSize.Values() {
+					for _, startMaxSize := range s.StartMaxSize.Values() {
+						for _, maxSize := range s.MaxSize.Values() {
+							for _, speed := range s.BaseSpeed.Values() {
+								combos = append(combos, Params{
+									Population:   int(population),
+									Teams:        int(teams),
+									Food:         int(food),
+									MinSize:      minSize,
+									StartMaxSize: startMaxSize,
+									MaxSize:      maxSize,
+									Speed:        speed,
+								})
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+	return combos
+}
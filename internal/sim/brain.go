@@ -0,0 +1,240 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Brain decides what an entity does each tick. Brains are called
+// concurrently for different entities, so any state they keep between calls
+// must be safe for that, and they must treat the perception as read-only.
+type Brain interface {
+	Decide(p *Perception) Decision
+}
+
+// Perception is what an entity can see of the world when it decides. Its
+// queries answer against the state at the start of the tick and only return
+// what lies inside the entity's view cone. Entities and
+// food returned by them must not be modified, and returned slices are only
+// valid until the next query.
+type Perception struct {
+	Self *Entity // The entity deciding
+
+	view *view
+}
+
+// EntitiesWithin returns the active entities within radius of Self.
+func (p *Perception) EntitiesWithin(radius float64) []*Entity {
+	radius = math.Min(radius, p.view.world.config.viewDistance())
+	return p.perceivedEntities(p.view.entitiesWithin(p.Self.X, p.Self.Y, radius))
+}
+
+// InjuredWithin returns the active entities below injured health within
+// radius of Self, of any team.
+func (p *Perception) InjuredWithin(radius float64) []*Entity {
+	radius = math.Min(radius, p.view.world.config.viewDistance())
+	return p.perceivedEntities(p.view.injuredWithin(p.Self.X, p.Self.Y, radius))
+}
+
+// NearestEntity returns the closest entity accepted by accept, or nil.
+func (p *Perception) NearestEntity(accept func(other *Entity) bool) *Entity {
+	if !p.omniscient() {
+		wanted := accept
+		accept = func(other *Entity) bool {
+			return wanted(other) && p.sees(other.X, other.Y)
+		}
+	}
+	return p.view.nearestEntity(p.Self.X, p.Self.Y, p.view.world.config.viewDistance(), accept)
+}
+
+// FoodWithin returns the active food within radius of Self.
+func (p *Perception) FoodWithin(radius float64) []*Food {
+	radius = math.Min(radius, p.view.world.config.viewDistance())
+	return p.perceivedFood(p.view.foodWithin(p.Self.X, p.Self.Y, radius))
+}
+
+// NearestFood returns the closest active food, or nil.
+func (p *Perception) NearestFood() *Food {
+	accept := func(f *Food) bool {
+		return f.Active
+	}
+	if !p.omniscient() {
+		accept = func(f *Food) bool {
+			return f.Active && p.sees(f.X, f.Y)
+		}
+	}
+	return p.view.nearestFood(p.Self.X, p.Self.Y, p.view.world.config.viewDistance(), accept)
+}
+
+// Bounds returns the width and height of the world.
+func (p *Perception) Bounds() (float64, float64) {
+	return p.view.world.canvasWidth, p.view.world.canvasHeight
+}
+
+// Tick returns the number of ticks run so far.
+func (p *Perception) Tick() int {
+	return p.view.world.ticks
+}
+
+// DefaultBrain is the original decision logic: flee larger enemies that get
+// too close, eat when starving, help nearby teammates in trouble, otherwise
+// hunt the closest weaker enemy.
+type DefaultBrain struct{}
+
+func (DefaultBrain) Decide(p *Perception) Decision {
+	e := p.Self
+	d := Decision{TeamNeed: e.EvaluateTeamNeed(p.InjuredWithin(teamNeedRange))}
+	// Distress calls from further away add to the need
+	d.TeamNeed = math.Min(100, d.TeamNeed+e.heardNeed(p.Inbox()))
+	if e.Health < injuredHealth {
+		d.Send = Message{Kind: DistressCall, X: e.X, Y: e.Y, Value: e.Health}
+	}
+
+	width, height := p.Bounds()
+	fleeRadius := e.Genome.PerceptionRadius
+	fleeX, fleeY, urgency := e.Sense(p.EntitiesWithin(fleeRadius), fleeRadius, width, height)
+	if urgency == 0 {
+		// Keep clear of threats seen recently, even once out of sight
+		fleeX, fleeY, urgency = e.avoidRemembered(p.Recall(EnemyMemory), fleeRadius, p.Tick())
+	}
+	d.FleeUrgency = urgency
+
+	// Simple decision criteria, with thresholds from the genome. Fleeing
+	// wins when it is more urgent than both hunger and the team's need.
+	if urgency > e.Genome.fleeThreshold() && urgency >= e.HungerLevel && urgency >= d.TeamNeed {
+		d.SteerX, d.SteerY = fleeX, fleeY
+		d.State = FleeState
+		d.Deposit, d.DepositRate = DangerPheromone, pheromoneDepositRate
+	} else if e.HungerLevel > e.Genome.HungerThreshold {
+		// If hunger is critical, prioritize seeking food
+		if food := p.NearestFood(); food != nil {
+			d.SteerX, d.SteerY = e.SeekFood([]*Food{food})
+			if e.world.distance(e.X, e.Y, food.X, food.Y) < e.Genome.PerceptionRadius {
+				// Mark the way for teammates
+				d.Deposit, d.DepositRate = FoodTrail, pheromoneDepositRate
+				if d.Send.Kind == NoMessage {
+					d.Send = Message{Kind: FoodHere, X: food.X, Y: food.Y, Subject: food.ID, Value: food.Size}
+				}
+			}
+		} else if m, ok := p.RecallNearest(FoodMemory); ok {
+			// Head back to food seen earlier
+			d.SteerX, d.SteerY = e.steerTowards(m.X, m.Y)
+		} else if m, ok := p.Heard(FoodHere); ok {
+			// Go where a teammate found food, along the team's shared route
+			d.SteerX, d.SteerY = p.FlowTowards(m.X, m.Y)
+		} else {
+			// Follow the trail teammates left to food
+			d.SteerX, d.SteerY = p.ScentGradient(FoodTrail)
+		}
+		d.State = SeekFoodState
+	} else if d.TeamNeed > e.Genome.assistThreshold() && e.TeamAssistTimeout <= 0 {
+		// If a teammate needs help, assist the teammate
+		d.Assist = e.AssistTeamMember(p.InjuredWithin(assistRange))
+		if m, ok := p.Heard(DistressCall); ok && d.Assist == nil {
+			// Too far to help yet, so answer the call
+			d.SteerX, d.SteerY = p.FlowTowards(m.X, m.Y)
+		}
+		d.State = AssistingTeamMemberState
+		d.Deposit, d.DepositRate = RallyPheromone, pheromoneDepositRate
+	} else {
+		// Default action
+		if enemy := p.NearestEntity(e.isWeakerEnemy); enemy != nil {
+			d.SteerX, d.SteerY = e.SeekWeakerEnemy([]*Entity{enemy})
+			if d.Send.Kind == NoMessage {
+				d.Send = Message{Kind: EnemySpotted, X: enemy.X, Y: enemy.Y, Subject: enemy.ID, Value: enemy.Width}
+			}
+		} else if m, ok := p.Heard(EnemySpotted); ok && m.Value < e.Width {
+			// Join the hunt a teammate called out
+			d.SteerX, d.SteerY = p.FlowTowards(m.X, m.Y)
+		} else {
+			// Nothing to hunt, so gather where teammates called for help
+			d.SteerX, d.SteerY = p.ScentGradient(RallyPheromone)
+		}
+		d.State = SeekWeakerEnemyState
+	}
+	return d
+}
+
+// SetTeamBrain assigns the brain every entity on a team decides with,
+// unless the entity has its own. Passing nil restores the default brain.
+func (w *World) SetTeamBrain(team int, b Brain) {
+	if w.teamBrains == nil {
+		w.teamBrains = make(map[int]Brain)
+	}
+	if b == nil {
+		delete(w.teamBrains, team)
+		return
+	}
+	w.teamBrains[team] = b
+}
+
+func (w *World) brainFor(e *Entity) Brain {
+	if e.brain != nil {
+		return e.brain
+	}
+	if b, ok := w.teamBrains[e.TeamID]; ok {
+		return b
+	}
+	return DefaultBrain{}
+}
+
+// LoadBrain builds a brain from a spec string:
+//
+//	default                 the DefaultBrain
+//	utility                 a UtilityBrain with the default curves
+//	utility:<config.json>   a UtilityBrain with curves loaded from a file
+//	bt                      a BehaviourTreeBrain running DefaultBehaviourTree
+//	bt:<tree.json>          a BehaviourTreeBrain running a tree from a file
+//	neural:<networks.json>  a NeuralBrain running the first network in a file
+func LoadBrain(spec string) (Brain, error) {
+	kind, arg, _ := strings.Cut(spec, ":")
+	switch kind {
+	case "", "default":
+		return DefaultBrain{}, nil
+	case "utility":
+		if arg == "" {
+			return NewUtilityBrain(DefaultUtilityConfig()), nil
+		}
+		c, err := LoadUtilityConfig(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewUtilityBrain(c), nil
+	case "bt":
+		if arg == "" {
+			return NewBehaviourTreeBrain(DefaultBehaviourTree()), nil
+		}
+		root, err := LoadBehaviourTree(arg)
+		if err != nil {
+			return nil, err
+		}
+		return NewBehaviourTreeBrain(root), nil
+	case "neural":
+		if arg == "" {
+			return nil, fmt.Errorf("neural brain needs a network file, e.g. neural:best.json")
+		}
+		networks, err := LoadNetworks(arg)
+		if err != nil {
+			return nil, err
+		}
+		if len(networks) == 0 {
+			return nil, fmt.Errorf("no networks in %s", arg)
+		}
+		return NewNeuralBrain(networks[0]), nil // Files are saved best first
+	}
+	return nil, fmt.Errorf("unknown brain %q", spec)
+}
+
+// LoadTeamBrains loads one brain per spec and assigns them to teams in
+// order. Teams beyond the last spec keep the default brain.
+func (w *World) LoadTeamBrains(specs []string) error {
+	for team, spec := range specs {
+		b, err := LoadBrain(spec)
+		if err != nil {
+			return fmt.Errorf("team %d: %w", team, err)
+		}
+		w.SetTeamBrain(team, b)
+	}
+	return nil
+}
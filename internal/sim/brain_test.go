@@ -0,0 +1,95 @@
+package sim
+
+import (
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestFile writes content to a file in a temporary directory and
+// returns its path.
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadBrain(t *testing.T) {
+	networks := filepath.Join(t.TempDir(), "networks.json")
+	rng := rand.New(rand.NewSource(1))
+	best := NewNetwork([]int{NeuralInputs, 4, NeuralOutputs}, rng)
+	if err := SaveNetworks(networks, []*Network{best, NewNetwork([]int{NeuralInputs, NeuralOutputs}, rng)}); err != nil {
+		t.Fatal(err)
+	}
+	empty := writeTestFile(t, "empty.json", `[]`)
+	utility := writeTestFile(t, "utility.json", `{"Hysteresis": 0.5}`)
+	tree := writeTestFile(t, "tree.json", `{"Type": "action", "Action": "wander"}`)
+
+	tests := []struct {
+		spec  string
+		check func(b Brain) bool
+	}{
+		{"", func(b Brain) bool { _, ok := b.(DefaultBrain); return ok }},
+		{"default", func(b Brain) bool { _, ok := b.(DefaultBrain); return ok }},
+		{"utility", func(b Brain) bool {
+			u, ok := b.(*UtilityBrain)
+			return ok && u.Config.Hysteresis == DefaultUtilityConfig().Hysteresis
+		}},
+		{"utility:" + utility, func(b Brain) bool {
+			u, ok := b.(*UtilityBrain)
+			return ok && u.Config.Hysteresis == 0.5
+		}},
+		{"bt", func(b Brain) bool {
+			bt, ok := b.(*BehaviourTreeBrain)
+			return ok && bt.Root.Name == "root"
+		}},
+		{"bt:" + tree, func(b Brain) bool {
+			bt, ok := b.(*BehaviourTreeBrain)
+			return ok && bt.Root.Action == "wander"
+		}},
+		{"neural:" + networks, func(b Brain) bool {
+			n, ok := b.(*NeuralBrain)
+			return ok && len(n.Net.Layers) == 3 // The first network in the file
+		}},
+	}
+	for _, test := range tests {
+		b, err := LoadBrain(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.spec, err)
+			continue
+		}
+		if !test.check(b) {
+			t.Errorf("%q: loaded the wrong brain %#v", test.spec, b)
+		}
+	}
+
+	for _, spec := range []string{
+		"psychic",
+		"neural",
+		"neural:" + empty,
+		"neural:" + filepath.Join(t.TempDir(), "missing.json"),
+		"utility:" + filepath.Join(t.TempDir(), "missing.json"),
+		"bt:" + writeTestFile(t, "bad.json", `{"Type": "action", "Action": "dance"}`),
+	} {
+		if _, err := LoadBrain(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestLoadTeamBrains(t *testing.T) {
+	w := NewWorld(DefaultConfig(), 1000, 600)
+	if err := w.LoadTeamBrains([]string{"utility", "default"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := w.teamBrains[0].(*UtilityBrain); !ok {
+		t.Errorf("team 0 has brain %#v, want a utility brain", w.teamBrains[0])
+	}
+	if err := w.LoadTeamBrains([]string{"default", "psychic"}); err == nil {
+		t.Error("expected an error for an unknown brain")
+	}
+}
@@ -0,0 +1,485 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Status is the result of ticking a behaviour tree node.
+type Status int
+
+const (
+	Failure Status = iota
+	Success
+	Running
+)
+
+// BTNode is a behaviour tree node as authored in JSON. Type is one of:
+//
+//	selector      ticks children in order until one does not fail
+//	sequence      ticks children in order until one does not succeed
+//	memselector   like selector, but resumes from a running child
+//	memsequence   like sequence, but resumes from a running child
+//	decorator     modifies its single child by Decorator: invert, succeed,
+//	              fail or cooldown (fails for Seconds after the child succeeds)
+//	condition     checks Condition: hungry, threatened, team_needs_help,
+//	              injured, prey_nearby, food_nearby, food_in_reach,
+//	              remembers_food, remembers_threat, smells_food,
+//	              smells_danger, smells_rally, heard_distress, heard_food,
+//	              heard_enemy
+//	action        performs Action: seek_food, assist, hunt, flee, wander, eat,
+//	              recall_food (head for the nearest remembered food),
+//	              follow_food_trail, avoid_danger, follow_rally, mark
+//	              (lay the Pheromone named food, danger or rally),
+//	              answer_distress, follow_food_call, follow_enemy_call, or
+//	              signal (send teammates the Message distress, food or enemy)
+//
+// Conditions compare against Threshold, or the entity's genome when it is
+// zero. prey_nearby and food_nearby use it as a radius.
+type BTNode struct {
+	Type      string
+	Name      string `json:",omitempty"`
+	Children  []*BTNode
+	Decorator string  `json:",omitempty"`
+	Condition string  `json:",omitempty"`
+	Action    string  `json:",omitempty"`
+	Threshold float64 `json:",omitempty"`
+	Seconds   float64 `json:",omitempty"`
+	Pheromone string  `json:",omitempty"`
+	Message   string  `json:",omitempty"`
+
+	id int // Preorder index, keys the node's per-entity state
+}
+
+func (n *BTNode) label() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	switch n.Type {
+	case "action":
+		return "action:" + n.Action
+	case "condition":
+		return "condition:" + n.Condition
+	case "decorator":
+		return "decorator:" + n.Decorator
+	}
+	return n.Type
+}
+
+var (
+	btConditions = map[string]bool{"hungry": true, "threatened": true, "team_needs_help": true, "injured": true, "prey_nearby": true, "food_nearby": true, "food_in_reach": true, "remembers_food": true, "remembers_threat": true,
+		"smells_food": true, "smells_danger": true, "smells_rally": true,
+		"heard_distress": true, "heard_food": true, "heard_enemy": true}
+	btActions = map[string]bool{"seek_food": true, "assist": true, "hunt": true, "flee": true, "wander": true, "eat": true, "recall_food": true,
+		"follow_food_trail": true, "avoid_danger": true, "follow_rally": true, "mark": true,
+		"answer_distress": true, "follow_food_call": true, "follow_enemy_call": true, "signal": true}
+	btPheromones = map[string]PheromoneKind{"food": FoodTrail, "danger": DangerPheromone, "rally": RallyPheromone}
+	btMessages   = map[string]MessageKind{"distress": DistressCall, "food": FoodHere, "enemy": EnemySpotted}
+	btHeard      = map[string]MessageKind{"heard_distress": DistressCall, "heard_food": FoodHere, "heard_enemy": EnemySpotted,
+		"answer_distress": DistressCall, "follow_food_call": FoodHere, "follow_enemy_call": EnemySpotted}
+	btDecorators = map[string]bool{"invert": true, "succeed": true, "fail": true, "cooldown": true}
+)
+
+// validate checks the tree and numbers its nodes.
+func (n *BTNode) validate(next *int) error {
+	n.id = *next
+	*next++
+	switch n.Type {
+	case "selector", "sequence", "memselector", "memsequence":
+		if len(n.Children) == 0 {
+			return fmt.Errorf("%s has no children", n.label())
+		}
+	case "decorator":
+		if !btDecorators[n.Decorator] {
+			return fmt.Errorf("unknown decorator %q", n.Decorator)
+		}
+		if len(n.Children) != 1 {
+			return fmt.Errorf("%s needs exactly one child", n.label())
+		}
+	case "condition":
+		if !btConditions[n.Condition] {
+			return fmt.Errorf("unknown condition %q", n.Condition)
+		}
+	case "action":
+		if !btActions[n.Action] {
+			return fmt.Errorf("unknown action %q", n.Action)
+		}
+		if _, ok := btPheromones[n.Pheromone]; n.Action == "mark" && !ok {
+			return fmt.Errorf("unknown pheromone %q", n.Pheromone)
+		}
+		if _, ok := btMessages[n.Message]; n.Action == "signal" && !ok {
+			return fmt.Errorf("unknown message %q", n.Message)
+		}
+	default:
+		return fmt.Errorf("unknown node type %q", n.Type)
+	}
+	for _, c := range n.Children {
+		if err := c.validate(next); err != nil {
+			return fmt.Errorf("%s: %w", n.label(), err)
+		}
+	}
+	return nil
+}
+
+// DefaultBehaviourTree is the default brain's logic as a tree.
+func DefaultBehaviourTree() *BTNode {
+	root := &BTNode{Type: "selector", Name: "root", Children: []*BTNode{
+		{Type: "sequence", Name: "survive", Children: []*BTNode{
+			{Type: "condition", Condition: "threatened"},
+			{Type: "action", Action: "flee"},
+		}},
+		{Type: "sequence", Name: "feed", Children: []*BTNode{
+			{Type: "condition", Condition: "hungry"},
+			{Type: "action", Action: "seek_food"},
+		}},
+		{Type: "sequence", Name: "help", Children: []*BTNode{
+			{Type: "condition", Condition: "team_needs_help"},
+			{Type: "decorator", Decorator: "cooldown", Seconds: 5, Children: []*BTNode{
+				{Type: "action", Action: "assist"},
+			}},
+		}},
+		{Type: "action", Action: "hunt"},
+	}}
+	next := 0
+	root.validate(&next)
+	return root
+}
+
+// LoadBehaviourTree reads a tree from a JSON file.
+func LoadBehaviourTree(path string) (*BTNode, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var root BTNode
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("parsing behaviour tree %s: %w", path, err)
+	}
+	next := 0
+	if err := root.validate(&next); err != nil {
+		return nil, fmt.Errorf("behaviour tree %s: %w", path, err)
+	}
+	return &root, nil
+}
+
+// btState is an entity's own state in a behaviour tree: the running child
+// of each memory composite and a blackboard nodes can share values on.
+type btState struct {
+	tree       *BTNode
+	running    map[int]int
+	Blackboard map[string]float64
+}
+
+// BehaviourTreeBrain runs a behaviour tree for each entity. Every entity
+// keeps its own blackboard and running nodes.
+type BehaviourTreeBrain struct {
+	Root *BTNode
+}
+
+func NewBehaviourTreeBrain(root *BTNode) *BehaviourTreeBrain {
+	return &BehaviourTreeBrain{Root: root}
+}
+
+// btTick carries what a single tick of the tree needs.
+type btTick struct {
+	p     *Perception
+	state *btState
+	d     *Decision
+	path  []string
+
+	// Computed on first use
+	fleeX, fleeY, urgency float64
+	sensed                bool
+}
+
+func (b *BehaviourTreeBrain) Decide(p *Perception) Decision {
+	e := p.Self
+	// Only the goroutine deciding for e touches its state, so this is safe
+	// to do while deciding.
+	state, ok := e.brainState.(*btState)
+	if !ok || state.tree != b.Root {
+		state = &btState{tree: b.Root, running: make(map[int]int), Blackboard: make(map[string]float64)}
+		e.brainState = state
+	}
+
+	d := Decision{TeamNeed: e.EvaluateTeamNeed(p.InjuredWithin(teamNeedRange)), State: e.State}
+	t := &btTick{p: p, state: state, d: &d}
+	t.tick(b.Root)
+	return d
+}
+
+func (t *btTick) tick(n *BTNode) Status {
+	t.path = append(t.path, n.label())
+	defer func() { t.path = t.path[:len(t.path)-1] }()
+
+	switch n.Type {
+	case "selector", "memselector":
+		start := 0
+		if n.Type == "memselector" {
+			start = t.state.running[n.id]
+		}
+		for i := start; i < len(n.Children); i++ {
+			if status := t.tick(n.Children[i]); status != Failure {
+				t.remember(n, i, status)
+				return status
+			}
+		}
+		delete(t.state.running, n.id)
+		return Failure
+	case "sequence", "memsequence":
+		start := 0
+		if n.Type == "memsequence" {
+			start = t.state.running[n.id]
+		}
+		for i := start; i < len(n.Children); i++ {
+			if status := t.tick(n.Children[i]); status != Success {
+				t.remember(n, i, status)
+				return status
+			}
+		}
+		delete(t.state.running, n.id)
+		return Success
+	case "decorator":
+		return t.decorate(n)
+	case "condition":
+		if t.condition(n) {
+			return Success
+		}
+		return Failure
+	case "action":
+		return t.action(n)
+	}
+	return Failure
+}
+
+// remember notes which child of a memory composite is running.
+func (t *btTick) remember(n *BTNode, child int, status Status) {
+	if status == Running && strings.HasPrefix(n.Type, "mem") {
+		t.state.running[n.id] = child
+	} else {
+		delete(t.state.running, n.id)
+	}
+}
+
+func (t *btTick) decorate(n *BTNode) Status {
+	switch n.Decorator {
+	case "invert":
+		switch t.tick(n.Children[0]) {
+		case Success:
+			return Failure
+		case Failure:
+			return Success
+		}
+		return Running
+	case "succeed":
+		t.tick(n.Children[0])
+		return Success
+	case "fail":
+		t.tick(n.Children[0])
+		return Failure
+	case "cooldown":
+		key := fmt.Sprintf("cooldown:%d", n.id)
+		now := float64(t.p.Tick()) * t.p.view.world.TickDuration()
+		if until, ok := t.state.Blackboard[key]; ok && now < until {
+			return Failure
+		}
+		status := t.tick(n.Children[0])
+		if status == Success {
+			t.state.Blackboard[key] = now + n.Seconds
+		}
+		return status
+	}
+	return Failure
+}
+
+func (t *btTick) sense() {
+	if !t.sensed {
+		e := t.p.Self
+		width, height := t.p.Bounds()
+		radius := e.Genome.PerceptionRadius
+		t.fleeX, t.fleeY, t.urgency = e.Sense(t.p.EntitiesWithin(radius), radius, width, height)
+		t.sensed = true
+	}
+}
+
+func (t *btTick) condition(n *BTNode) bool {
+	e := t.p.Self
+	threshold := func(fallback float64) float64 {
+		if n.Threshold != 0 {
+			return n.Threshold
+		}
+		return fallback
+	}
+	switch n.Condition {
+	case "hungry":
+		return e.HungerLevel > threshold(e.Genome.HungerThreshold)
+	case "threatened":
+		t.sense()
+		t.d.FleeUrgency = t.urgency
+		return t.urgency > threshold(e.Genome.fleeThreshold())
+	case "team_needs_help":
+		return t.d.TeamNeed > threshold(e.Genome.assistThreshold())
+	case "injured":
+		return e.Health < threshold(injuredHealth)
+	case "prey_nearby":
+		prey := t.p.NearestEntity(e.isWeakerEnemy)
+		return prey != nil && e.DistanceTo(prey) < threshold(e.Genome.PerceptionRadius)
+	case "food_nearby":
+		food := t.p.NearestFood()
+		return food != nil && e.world.distance(e.X, e.Y, food.X, food.Y) < threshold(e.Genome.PerceptionRadius)
+	case "food_in_reach":
+		return len(t.p.FoodWithin(e.foodReach())) > 0
+	case "remembers_food":
+		_, ok := t.p.RecallNearest(FoodMemory)
+		return ok
+	case "remembers_threat":
+		_, _, urgency := e.avoidRemembered(t.p.Recall(EnemyMemory), e.Genome.PerceptionRadius, t.p.Tick())
+		return urgency > threshold(0)
+	case "smells_food":
+		return t.p.Scent(FoodTrail) > threshold(0)
+	case "smells_danger":
+		return t.p.Scent(DangerPheromone) > threshold(0)
+	case "smells_rally":
+		return t.p.Scent(RallyPheromone) > threshold(0)
+	case "heard_distress", "heard_food", "heard_enemy":
+		_, ok := t.p.Heard(btHeard[n.Condition])
+		return ok
+	}
+	return false
+}
+
+// act records the leaf that produced the decision.
+func (t *btTick) act(state State) {
+	t.d.State = state
+	t.d.NodePath = strings.Join(t.path, "/")
+}
+
+func (t *btTick) action(n *BTNode) Status {
+	e := t.p.Self
+	switch n.Action {
+	case "seek_food":
+		food := t.p.NearestFood()
+		if food == nil {
+			return Failure
+		}
+		t.d.SteerX, t.d.SteerY = e.SeekFood([]*Food{food})
+		if e.world.distance(e.X, e.Y, food.X, food.Y) < e.Genome.PerceptionRadius {
+			t.d.Deposit, t.d.DepositRate = FoodTrail, pheromoneDepositRate
+		}
+		t.act(SeekFoodState)
+		return Running
+	case "follow_food_trail", "avoid_danger", "follow_rally":
+		return t.follow(n.Action)
+	case "answer_distress", "follow_food_call", "follow_enemy_call":
+		m, ok := t.p.Heard(btHeard[n.Action])
+		if !ok {
+			return Failure
+		}
+		t.d.SteerX, t.d.SteerY = t.p.FlowTowards(m.X, m.Y)
+		t.act(map[MessageKind]State{DistressCall: AssistingTeamMemberState, FoodHere: SeekFoodState, EnemySpotted: SeekWeakerEnemyState}[m.Kind])
+		return Running
+	case "signal":
+		return t.signal(btMessages[n.Message])
+	case "mark":
+		t.d.Deposit, t.d.DepositRate = btPheromones[n.Pheromone], pheromoneDepositRate
+		return Success
+	case "recall_food":
+		m, ok := t.p.RecallNearest(FoodMemory)
+		if !ok {
+			return Failure
+		}
+		t.d.SteerX, t.d.SteerY = e.steerTowards(m.X, m.Y)
+		t.act(SeekFoodState)
+		return Running
+	case "eat":
+		// Eating itself happens once decisions are applied
+		if len(t.p.FoodWithin(e.foodReach())) == 0 {
+			return Failure
+		}
+		t.act(SeekFoodState)
+		return Success
+	case "assist":
+		if e.TeamAssistTimeout > 0 {
+			return Failure
+		}
+		teammate := e.AssistTeamMember(t.p.InjuredWithin(assistRange))
+		if teammate == nil {
+			return Failure
+		}
+		t.d.Assist = teammate
+		t.d.Deposit, t.d.DepositRate = RallyPheromone, pheromoneDepositRate
+		t.act(AssistingTeamMemberState)
+		return Success
+	case "hunt":
+		prey := t.p.NearestEntity(e.isWeakerEnemy)
+		if prey == nil {
+			return Failure
+		}
+		t.d.SteerX, t.d.SteerY = e.SeekWeakerEnemy([]*Entity{prey})
+		t.act(SeekWeakerEnemyState)
+		return Running
+	case "flee":
+		t.sense()
+		if t.urgency == 0 {
+			return Failure
+		}
+		t.d.SteerX, t.d.SteerY, t.d.FleeUrgency = t.fleeX, t.fleeY, t.urgency
+		t.d.Deposit, t.d.DepositRate = DangerPheromone, pheromoneDepositRate
+		t.act(FleeState)
+		return Running
+	case "wander":
+		t.d.SteerX, t.d.SteerY = e.wander(t.p.Tick())
+		t.act(WanderState)
+		return Running
+	}
+	return Failure
+}
+
+// follow steers along a pheromone gradient, failing where there is none.
+func (t *btTick) follow(action string) Status {
+	var x, y float64
+	var state State
+	switch action {
+	case "follow_food_trail":
+		x, y = t.p.ScentGradient(FoodTrail)
+		state = SeekFoodState
+	case "avoid_danger":
+		x, y = t.p.ScentGradient(DangerPheromone)
+		x, y = -x, -y
+		state = FleeState
+	case "follow_rally":
+		x, y = t.p.ScentGradient(RallyPheromone)
+		state = AssistingTeamMemberState
+	}
+	if x == 0 && y == 0 {
+		return Failure
+	}
+	t.d.SteerX, t.d.SteerY = x, y
+	t.act(state)
+	return Running
+}
+
+// signal sends teammates a message about Self, the nearest food or the
+// nearest weaker enemy, failing when there is nothing to report.
+func (t *btTick) signal(kind MessageKind) Status {
+	e := t.p.Self
+	switch kind {
+	case DistressCall:
+		t.d.Send = Message{Kind: kind, X: e.X, Y: e.Y, Value: e.Health}
+	case FoodHere:
+		food := t.p.NearestFood()
+		if food == nil {
+			return Failure
+		}
+		t.d.Send = Message{Kind: kind, X: food.X, Y: food.Y, Subject: food.ID, Value: food.Size}
+	case EnemySpotted:
+		enemy := t.p.NearestEntity(e.isWeakerEnemy)
+		if enemy == nil {
+			return Failure
+		}
+		t.d.Send = Message{Kind: kind, X: enemy.X, Y: enemy.Y, Subject: enemy.ID, Value: enemy.Width}
+	}
+	return Success
+}
@@ -0,0 +1,75 @@
+package sim
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadBehaviourTree(t *testing.T) {
+	root, err := LoadBehaviourTree(writeTestFile(t, "tree.json", `{
+		"Type": "selector", "Children": [
+			{"Type": "sequence", "Children": [
+				{"Type": "condition", "Condition": "hungry"},
+				{"Type": "action", "Action": "mark", "Pheromone": "food"}
+			]},
+			{"Type": "decorator", "Decorator": "cooldown", "Seconds": 2, "Children": [
+				{"Type": "action", "Action": "signal", "Message": "enemy"}
+			]}
+		]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Nodes are numbered in preorder
+	ids := []int{root.id, root.Children[0].id, root.Children[0].Children[0].id, root.Children[0].Children[1].id,
+		root.Children[1].id, root.Children[1].Children[0].id}
+	for want, id := range ids {
+		if id != want {
+			t.Errorf("node numbered %d, want %d", id, want)
+		}
+	}
+
+	if _, err := LoadBehaviourTree(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+	if _, err := LoadBehaviourTree(writeTestFile(t, "tree.json", `{"Type": `)); err == nil {
+		t.Error("not json: expected an error")
+	}
+}
+
+func TestBehaviourTreeValidate(t *testing.T) {
+	wander := &BTNode{Type: "action", Action: "wander"}
+	tests := []struct {
+		name  string
+		node  *BTNode
+		valid bool
+	}{
+		{"default tree", DefaultBehaviourTree(), true},
+		{"action", &BTNode{Type: "action", Action: "hunt"}, true},
+		{"memsequence", &BTNode{Type: "memsequence", Children: []*BTNode{wander}}, true},
+		{"unknown type", &BTNode{Type: "parallel"}, false},
+		{"composite without children", &BTNode{Type: "selector"}, false},
+		{"unknown decorator", &BTNode{Type: "decorator", Decorator: "repeat", Children: []*BTNode{wander}}, false},
+		{"decorator without child", &BTNode{Type: "decorator", Decorator: "invert"}, false},
+		{"decorator with two children", &BTNode{Type: "decorator", Decorator: "invert", Children: []*BTNode{wander, wander}}, false},
+		{"unknown condition", &BTNode{Type: "condition", Condition: "bored"}, false},
+		{"unknown action", &BTNode{Type: "action", Action: "dance"}, false},
+		{"mark without pheromone", &BTNode{Type: "action", Action: "mark"}, false},
+		{"mark with unknown pheromone", &BTNode{Type: "action", Action: "mark", Pheromone: "fear"}, false},
+		{"signal without message", &BTNode{Type: "action", Action: "signal"}, false},
+		{"signal", &BTNode{Type: "action", Action: "signal", Message: "distress"}, true},
+		{"bad grandchild", &BTNode{Type: "sequence", Children: []*BTNode{
+			{Type: "selector", Children: []*BTNode{{Type: "condition", Condition: "bored"}}},
+		}}, false},
+	}
+	for _, test := range tests {
+		next := 0
+		err := test.node.validate(&next)
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
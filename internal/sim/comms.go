@@ -0,0 +1,133 @@
+package sim
+
+import "math"
+
+// MessageKind is what a message between teammates says.
+type MessageKind int
+
+const (
+	NoMessage    MessageKind = iota
+	DistressCall             // The sender is injured at X, Y
+	FoodHere                 // Food is at X, Y
+	EnemySpotted             // An enemy of width Value is at X, Y
+)
+
+// Message is a signal sent to teammates. Brains fill in Kind, X, Y,
+// Subject and Value; the world fills in the rest when it is sent.
+type Message struct {
+	Kind    MessageKind
+	X, Y    float64 // Where the message is about
+	Subject int     // ID of the food or entity it is about, if any
+	Value   float64 // Sender's health for distress calls, enemy width for sightings
+
+	From         int     // ID of the sender
+	Team         int     // Team of the sender
+	FromX, FromY float64 // Where the sender was
+	Sent         int     // Tick it was sent
+	deliver      int     // Tick it arrives
+}
+
+const (
+	defaultMessageRange    = 300.0
+	defaultMessageLatency  = 0.25
+	defaultMessageInterval = 1.0
+	messageLifetime        = 2.0 // Seconds a message stays in an inbox
+	maxInbox               = 8   // Messages an entity can hold, oldest dropped first
+)
+
+// send queues an entity's message for its teammates, unless it sent one
+// too recently.
+func (w *World) send(e *Entity, m Message) {
+	if !w.config.Communication || m.Kind == NoMessage || w.ticks < e.nextMessage {
+		return
+	}
+	tick := w.TickDuration()
+	latency := int(math.Ceil(orDefault(w.config.MessageLatency, defaultMessageLatency) / tick))
+	m.From, m.Team = e.ID, e.TeamID
+	m.FromX, m.FromY = e.X, e.Y
+	m.Sent = w.ticks
+	m.deliver = w.ticks + max(1, latency) // Decisions this tick are already made
+	w.messages = append(w.messages, m)
+	e.nextMessage = w.ticks + int(math.Ceil(orDefault(w.config.MessageInterval, defaultMessageInterval)/tick))
+}
+
+// deliverMessages drops stale messages from inboxes, then delivers the
+// messages that are due to every active teammate in range of where the
+// sender was. It runs after the index is rebuilt and before entities
+// decide.
+func (w *World) deliverMessages() {
+	if !w.config.Communication {
+		return
+	}
+	lifetime := int(math.Ceil(messageLifetime / w.TickDuration()))
+	for _, e := range w.entities {
+		kept := e.inbox[:0]
+		for _, m := range e.inbox {
+			if w.ticks-m.deliver < lifetime {
+				kept = append(kept, m)
+			}
+		}
+		e.inbox = kept
+	}
+
+	reach := orDefault(w.config.MessageRange, defaultMessageRange)
+	if len(w.views) == 0 {
+		w.views = append(w.views, view{world: w})
+	}
+	v := &w.views[0]
+	pending := w.messages[:0]
+	for _, m := range w.messages {
+		if m.deliver > w.ticks {
+			pending = append(pending, m)
+			continue
+		}
+		v.indices = w.entityGrid.within(m.FromX, m.FromY, reach, v.indices[:0])
+		for _, i := range v.indices {
+			if e := w.entities[i]; e.Active && e.TeamID == m.Team && e.ID != m.From {
+				e.receive(m)
+			}
+		}
+	}
+	w.messages = pending
+}
+
+func (e *Entity) receive(m Message) {
+	if len(e.inbox) == maxInbox {
+		copy(e.inbox, e.inbox[1:])
+		e.inbox = e.inbox[:maxInbox-1]
+	}
+	e.inbox = append(e.inbox, m)
+}
+
+// Inbox returns the messages Self has received recently, oldest first.
+func (p *Perception) Inbox() []Message {
+	return p.Self.inbox
+}
+
+// Heard returns the message of a kind about the place closest to Self.
+func (p *Perception) Heard(kind MessageKind) (Message, bool) {
+	var nearest Message
+	found := false
+	best := math.Inf(1)
+	for _, m := range p.Self.inbox {
+		if m.Kind != kind {
+			continue
+		}
+		if d := p.view.world.distance(p.Self.X, p.Self.Y, m.X, m.Y); d < best {
+			nearest, found, best = m, true, d
+		}
+	}
+	return nearest, found
+}
+
+// heardNeed scores distress calls from teammates too far away for
+// EvaluateTeamNeed to have noticed, on the same scale.
+func (e *Entity) heardNeed(inbox []Message) float64 {
+	need := 0.0
+	for _, m := range inbox {
+		if m.Kind == DistressCall && e.world.distance(e.X, e.Y, m.X, m.Y) >= teamNeedRange {
+			need += math.Max(0, injuredHealth-m.Value)
+		}
+	}
+	return need
+}
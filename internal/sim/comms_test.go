@@ -0,0 +1,33 @@
+package sim
+
+import "testing"
+
+func TestDeliverMessagesToActiveTeammates(t *testing.T) {
+	c := DefaultConfig()
+	c.Seed = 1
+	c.Communication = true
+	w := NewWorld(c, 1000, 600)
+	w.SetLogOutput(nil)
+	w.InitializeEntities(4, 1)
+	entities := w.GetEntities()
+	for i, e := range entities {
+		e.X, e.Y = 500+float64(i)*10, 300
+	}
+	sender, teammate, dead, outOfRange := entities[0], entities[1], entities[2], entities[3]
+	outOfRange.X = 500 + defaultMessageRange + 50
+
+	w.send(sender, Message{Kind: FoodHere, X: 100, Y: 100})
+	w.rebuildIndex()
+	dead.SetActive(false) // Dies after the index is built
+	w.ticks += 60
+	w.deliverMessages()
+
+	if len(teammate.inbox) != 1 {
+		t.Errorf("teammate in range has %d messages, want 1", len(teammate.inbox))
+	}
+	for _, e := range []*Entity{sender, dead, outOfRange} {
+		if len(e.inbox) != 0 {
+			t.Errorf("entity %d has %d messages, want none", e.ID, len(e.inbox))
+		}
+	}
+}
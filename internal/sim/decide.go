@@ -0,0 +1,58 @@
+package sim
+
+import (
+	"runtime"
+	"sync"
+)
+
+// minDecisionsPerWorker keeps small worlds from paying for goroutines they
+// do not need.
+const minDecisionsPerWorker = 256
+
+// decide fills w.decisions for every active entity. The entities are split
+// into contiguous chunks, one per worker, and nothing in the world is
+// written until every worker is done.
+func (w *World) decide() {
+	if cap(w.decisions) < len(w.entities) {
+		w.decisions = make([]Decision, len(w.entities))
+	}
+	w.decisions = w.decisions[:len(w.entities)]
+
+	workers := runtime.GOMAXPROCS(0)
+	if max := (len(w.entities) + minDecisionsPerWorker - 1) / minDecisionsPerWorker; workers > max {
+		workers = max
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for len(w.views) < workers {
+		w.views = append(w.views, view{world: w})
+	}
+
+	memory := w.memorySpan() > 0
+	chunk := (len(w.entities) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		start := worker * chunk
+		end := start + chunk
+		if end > len(w.entities) {
+			end = len(w.entities)
+		}
+		wg.Add(1)
+		go func(v *view, start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				if e := w.entities[i]; e.Active {
+					p := &Perception{Self: e, view: v}
+					if memory {
+						p.observe()
+					}
+					w.decisions[i] = e.DecideAction(p)
+				} else {
+					w.decisions[i] = Decision{}
+				}
+			}
+		}(&w.views[worker], start, end)
+	}
+	wg.Wait()
+}
@@ -1,7 +1,6 @@
 package sim
 
 import (
-	"fmt"
 	"math"
 )
 
@@ -11,12 +10,22 @@ const (
 	SeekFoodState            State = "SeekFood"
 	AssistingTeamMemberState State = "AssistingTeamMember"
 	SeekWeakerEnemyState     State = "AssistingWeakerEnemyState"
+	FleeState                State = "Flee"
+)
+
+const (
+	teamNeedRange = 100.0 // Distance teammates in trouble are noticed from
+	assistRange   = 4.0   // Distance a teammate must be within to be assisted
+	injuredHealth = 50.0  // Health below which teammates need help
+
+	defaultFleeRadius = 100.0 // Distance larger enemies are fled from
 )
 
 type Entity struct {
 	ID                int     // Unique identifier for the entity
 	X, Y              float64 // Position of the entity
 	VX, VY            float64 // Velocity of the entity
+	Heading           float64 // Direction the entity faces, in radians
 	Width             float64 // Width of the entity (if it's rectangular)
 	Height            float64 // Height of the entity (if it's rectangular)
 	Active            bool    // Whether the entity is active in the simulation
@@ -24,34 +33,109 @@ type Entity struct {
 	MaxHealth         float64
 	Invulnerable      bool    // Whether the entity is currently invulnerable
 	InvulnTimer       float64 // Time remaining in the invulnerable state
-	HungerLevel       float64
+	HungerLevel       float64 // 0 when full of energy, 100 when out of it
+	Energy            float64
 	TeamID            int
 	TeamNeed          float64
+	FleeUrgency       float64
 	TeamTimeout       bool
 	TeamAssistTimeout float64
 	State             State
+	NodePath          string // Behaviour tree nodes behind the current state
+	Genome            Genome
+	ParentID          int     // ID of the entity that gave birth to this one, 0 for the first generation
+	Generation        int     // Births between this entity and the first generation
+	BirthCooldown     float64 // Seconds until the entity can give birth again
+
+	world     *World // World the entity belongs to
+	bornAt    int    // Tick the entity was born at, 0 for the first generation
+	diedAt    int    // Tick the entity was deactivated at
+	brain     Brain  // Overrides the team brain when set
+	kills     int    // Enemies this entity reduced to zero health
+	foodEaten int
+
+	// brainState belongs to the entity's brain. It is only touched while
+	// the entity decides, which never happens on two goroutines at once.
+	brainState any
+
+	// memories is what the entity remembers seeing. Like brainState it is
+	// only written while the entity decides.
+	memories []Memory
+
+	inbox       []Message // Messages from teammates, delivered between ticks
+	nextMessage int       // Tick the entity may next send a message
+
+	formationSlot int // Place around the team leader when in formation
+
+	path pathPlan // Way to the last target an obstacle stood in front of
+
+	ax, ay float64 // Acceleration the entity moves with under Config.Kinematics
+}
+
+// Decision is what an entity intends to do in a tick. Decisions are made
+// against the state of the world at the start of the tick and applied once
+// every entity has decided, so no entity sees another's half-finished tick.
+type Decision struct {
+	State          State
+	NodePath       string // Behaviour tree path to the deciding node, if any
+	TeamNeed       float64
+	FleeUrgency    float64
+	SteerX, SteerY float64 // Change in velocity, or desired acceleration under Config.Kinematics
+	Assist         *Entity // Teammate to assist, nil for none
+
+	Deposit     PheromoneKind // Pheromone to lay where the entity is
+	DepositRate float64       // Amount laid per second, zero for none
+
+	Send Message // Message to teammates, NoMessage for none
+}
+
+// DecideAction asks the entity's brain what to do this tick. It only reads
+// world state, so entities can decide concurrently.
+func (e *Entity) DecideAction(p *Perception) Decision {
+	d := e.world.brainFor(e).Decide(p)
+	if f, ok := e.world.config.flockingFor(e.TeamID); ok {
+		p.flock(f, &d)
+	}
+	return d
+}
+
+// SetBrain overrides the brain the entity decides with. Passing nil falls
+// back to its team's brain.
+func (e *Entity) SetBrain(b Brain) {
+	e.brain = b
 }
 
-func (e *Entity) DecideAction(entities []*Entity, foods []*Food) {
-	// Simple decision criteria
-	if e.HungerLevel > 80 {
-		// If hunger is critical, prioritize seeking food
-		e.SeekFood(foods)
-		e.State = SeekFoodState
-	} else if e.TeamNeed > 50 && e.TeamAssistTimeout <= 0 {
-		// If a teammate needs help, assist the teammate
-		e.AssistTeamMember(entities)
-		e.State = AssistingTeamMemberState
+// ApplyDecision carries out a decision made earlier in the tick.
+func (e *Entity) ApplyDecision(d Decision) {
+	e.TeamNeed = d.TeamNeed
+	e.FleeUrgency = d.FleeUrgency
+	e.State = d.State
+	e.NodePath = d.NodePath
+	if e.world.config.Kinematics {
+		e.accelerate(d.SteerX, d.SteerY)
 	} else {
-		// Default action
-		e.SeekWeakerEnemy(entities)
-		e.State = SeekWeakerEnemyState
+		e.VX += d.SteerX
+		e.VY += d.SteerY
+	}
+
+	// If a teammate was found, perform an assist action
+	if d.Assist != nil {
+		e.PerformAssistAction(d.Assist)
+		e.TeamTimeout = true
+		e.TeamAssistTimeout = 5.0
 	}
 }
 
-func (e *Entity) SeekWeakerEnemy(entities []*Entity) {
+// isWeakerEnemy reports whether other is an active, smaller entity on
+// another team.
+func (e *Entity) isWeakerEnemy(other *Entity) bool {
+	return other.Active && other.ID != e.ID && other.TeamID != e.TeamID && other.Width < e.Width
+}
+
+// SeekWeakerEnemy returns the steering towards the closest weaker enemy.
+func (e *Entity) SeekWeakerEnemy(entities []*Entity) (float64, float64) {
 	if !e.Active {
-		return // Skip if the entity is not active
+		return 0, 0 // Skip if the entity is not active
 	}
 
 	// Find the closest active food
@@ -60,13 +144,14 @@ func (e *Entity) SeekWeakerEnemy(entities []*Entity) {
 
 	for i := range entities {
 		other := entities[i]
-		if !other.Active || other.ID == e.ID || other.TeamID == e.TeamID || other.Width >= e.Width {
-			continue // Skip inactive food
+		if !e.isWeakerEnemy(other) {
+			continue // Skip anything that is not a weaker enemy
 		}
 
 		// Calculate squared distance to the e
-		dx := e.X - e.X
-		dy := e.Y - e.Y
+		ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+		dx := ox - e.X
+		dy := oy - e.Y
 		distanceSquared := dx*dx + dy*dy
 
 		// Check if this is the closest e found so far
@@ -78,81 +163,88 @@ func (e *Entity) SeekWeakerEnemy(entities []*Entity) {
 
 	// Move towards the closest e if found
 	if closestWeakerEnemy != nil {
-		dx := closestWeakerEnemy.X - e.X
-		dy := closestWeakerEnemy.Y - e.Y
-
-		// Normalize the direction and adjust velocity using pythagoras
-		length := math.Sqrt(dx*dx + dy*dy)
-		if length != 0 {
-			// TODO: Scale on hunger need?
-			e.VX += (dx / length) * 0.1 // Scale speed towards the food
-			e.VY += (dy / length) * 0.1
-		}
+		// TODO: Scale on hunger need?
+		return e.steerTowards(closestWeakerEnemy.X, closestWeakerEnemy.Y)
 	}
+	return 0, 0
 }
 
-func (e *Entity) EvaluateTeamNeed(entities []*Entity) {
-	// Reset TeamNeed to avoid carrying over previous values
-	e.TeamNeed = 0
+// EvaluateTeamNeed scores how badly nearby teammates need help.
+func (e *Entity) EvaluateTeamNeed(entities []*Entity) float64 {
+	teamNeed := 0.0
 
 	// Iterate over all entities to evaluate teammates' needs
 	for _, teammate := range entities {
 		// Check if the entity is on the same team, is not itself, and has low health
-		if teammate.TeamID == e.TeamID && teammate != e && teammate.Health < 50 {
+		if teammate.TeamID == e.TeamID && teammate != e && teammate.Health < injuredHealth {
 			// Calculate the distance to the teammate
 			distance := e.DistanceTo(teammate)
 
 			// If the teammate is within a certain range, increase the TeamNeed score
-			if distance < 100.0 { // Example range threshold
+			if distance < teamNeedRange {
 				// Increase TeamNeed based on the severity of the teammate's condition
-				e.TeamNeed += 50.0 - teammate.Health // The lower the health, the higher the need
+				teamNeed += 50.0 - teammate.Health // The lower the health, the higher the need
 			}
 		}
 	}
 
 	// Cap TeamNeed to a maximum value if needed
-	if e.TeamNeed > 100 {
-		e.TeamNeed = 100
+	if teamNeed > 100 {
+		teamNeed = 100
 	}
+	return teamNeed
 }
 
-func (e *Entity) Sense(nearbyEntities []Entity, canvasWidth, canvasHeight float64) {
+// Sense looks for larger enemies within fleeRadius and returns the steering
+// away from them and how urgently the entity wants to flee, from 0 to 100.
+// Closer threats weigh more. Near the canvas edges the steering also pushes
+// back towards the middle so fleeing entities are not pinned into corners.
+func (e *Entity) Sense(nearbyEntities []*Entity, fleeRadius, canvasWidth, canvasHeight float64) (float64, float64, float64) {
+	var steerX, steerY, urgency float64
 	for _, other := range nearbyEntities {
-		if other.ID == e.ID || other.TeamID == e.TeamID || !other.Active {
-			continue // Skip self or same team or inactive entities
+		if other.ID == e.ID || other.TeamID == e.TeamID || !other.Active || other.Width <= e.Width {
+			continue // Skip self, same team, inactive or smaller entities
 		}
 
 		// Calculate the distance to the other entity
-		dx := other.X - e.X
-		dy := other.Y - e.Y
-		distance := dx*dx + dy*dy // Squared distance for efficiency
-
-		// Flee behavior: if the other entity is larger, move away
-		if other.Width > e.Width {
-			if distance < 10000 { // Threshold for fleeing behavior
-				e.VX -= dx / 50 // Move away from the other entity
-				e.VY -= dy / 50
-			}
-		} else {
-			// Seek behavior: if the other entity is smaller, move towards it
-			if distance < 40000 { // Threshold for seeking behavior
-				e.VX += dx / 50 // Move towards the other entity
-				e.VY += dy / 50
-			}
+		ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+		dx := ox - e.X
+		dy := oy - e.Y
+		distance := math.Sqrt(dx*dx + dy*dy)
+		if distance == 0 || distance >= fleeRadius {
+			continue
 		}
+
+		// Flee behavior: move away, harder the closer the threat is
+		closeness := 1 - distance/fleeRadius
+		steerX -= (dx / distance) * closeness
+		steerY -= (dy / distance) * closeness
+		urgency += 100 * closeness
+	}
+	if urgency == 0 {
+		return 0, 0, 0
 	}
 
-	// Sense the boundaries and adjust velocity to avoid going out of bounds
-	if e.X < 50 {
-		e.VX += 1 // Move right
-	} else if e.X+e.Width > canvasWidth-50 {
-		e.VX -= 1 // Move left
+	// Sense the boundaries and steer away from them while fleeing. A
+	// wrapping arena has none.
+	if !e.world.config.Wrap {
+		if e.X < 50 {
+			steerX += 1 // Move right
+		} else if e.X+e.Width > canvasWidth-50 {
+			steerX -= 1 // Move left
+		}
+		if e.Y < 50 {
+			steerY += 1 // Move down
+		} else if e.Y+e.Height > canvasHeight-50 {
+			steerY -= 1 // Move up
+		}
 	}
-	if e.Y < 50 {
-		e.VY += 1 // Move down
-	} else if e.Y+e.Height > canvasHeight-50 {
-		e.VY -= 1 // Move up
+
+	// Scale to the same nudge as the other behaviours
+	if length := math.Sqrt(steerX*steerX + steerY*steerY); length != 0 {
+		steerX, steerY = (steerX/length)*0.1, (steerY/length)*0.1
 	}
+	return steerX, steerY, math.Min(urgency, 100)
 }
 
 func (e *Entity) Act(nearbyEntities []*Entity, canvasWidth, canvasHeight, deltaTime float64) {
@@ -167,7 +259,7 @@ func (e *Entity) Act(nearbyEntities []*Entity, canvasWidth, canvasHeight, deltaT
 		if e.InvulnTimer <= 0 {
 			e.Invulnerable = false
 			e.InvulnTimer = 0
-			fmt.Printf("Entity %d is no longer invulnerable.\n", e.ID)
+			e.world.logf("Entity %d is no longer invulnerable.\n", e.ID)
 		}
 		return
 	}
@@ -177,49 +269,67 @@ func (e *Entity) Act(nearbyEntities []*Entity, canvasWidth, canvasHeight, deltaT
 		if e.TeamAssistTimeout <= 0 {
 			e.TeamTimeout = false
 			e.TeamAssistTimeout = 0
-			fmt.Printf("Entity %d is no longer on team timeout.\n", e.ID)
+			e.world.logf("Entity %d is no longer on team timeout.\n", e.ID)
 		}
 	}
 
-	// Step 3: Update position based on velocity
-	e.X += e.VX * deltaTime
-	e.Y += e.VY * deltaTime
+	// Step 3: Update position based on velocity, facing the way it moves
+	if e.world.config.Kinematics {
+		// Accelerate, turn and cap the speed before moving
+		e.integrate(deltaTime)
+		e.X += e.VX * deltaTime
+		e.Y += e.VY * deltaTime
+	} else {
+		e.X += e.VX * deltaTime
+		e.Y += e.VY * deltaTime
+		if e.VX != 0 || e.VY != 0 {
+			e.Heading = math.Atan2(e.VY, e.VX)
+		}
 
-	// Step 4: Limit the speed based on the size of the entity
-	sizeFactor := 1.0 / (1.0 + (e.Width / 100.0)) // Speed decreases as size increases
-	maxSpeed := config.BaseSpeed * sizeFactor
+		// Step 4: Limit the speed based on the size of the entity
+		maxSpeed := e.maxSpeed()
 
-	// Cap the velocity components to the maximum speed
-	e.VX = clamp(e.VX, -maxSpeed, maxSpeed)
-	e.VY = clamp(e.VY, -maxSpeed, maxSpeed)
+		// Cap the velocity components to the maximum speed
+		e.VX = clamp(e.VX, -maxSpeed, maxSpeed)
+		e.VY = clamp(e.VY, -maxSpeed, maxSpeed)
+	}
 
 	// Step 5: Keep the entity within the canvas boundaries
-	if e.X < 0 {
-		e.X = 0
-		e.VX = -e.VX // Reverse direction upon hitting the left boundary
-	} else if e.X+e.Width > canvasWidth {
-		e.X = canvasWidth - e.Width
-		e.VX = -e.VX // Reverse direction upon hitting the right boundary
-	}
+	if e.world.config.Wrap {
+		e.wrapAround(canvasWidth, canvasHeight)
+	} else if e.world.config.Collisions {
+		e.collideWalls(canvasWidth, canvasHeight)
+	} else {
+		if e.X < 0 {
+			e.X = 0
+			e.VX = -e.VX // Reverse direction upon hitting the left boundary
+		} else if e.X+e.Width > canvasWidth {
+			e.X = canvasWidth - e.Width
+			e.VX = -e.VX // Reverse direction upon hitting the right boundary
+		}
 
-	if e.Y < 0 {
-		e.Y = 0
-		e.VY = -e.VY // Reverse direction upon hitting the top boundary
-	} else if e.Y+e.Height > canvasHeight {
-		e.Y = canvasHeight - e.Height
-		e.VY = -e.VY // Reverse direction upon hitting the bottom boundary
+		if e.Y < 0 {
+			e.Y = 0
+			e.VY = -e.VY // Reverse direction upon hitting the top boundary
+		} else if e.Y+e.Height > canvasHeight {
+			e.Y = canvasHeight - e.Height
+			e.VY = -e.VY // Reverse direction upon hitting the bottom boundary
+		}
 	}
 
+	// Step 5b: Slide along any obstacles rather than passing through
+	e.resolveObstacles()
+
 	// Step 6: Interact with nearby entities (consume behavior)
 	e.Consume(nearbyEntities)
 
-	if e.HungerLevel > 0 {
-		e.HungerLevel += 1.0
-	}
-	// Step 7: Deactivate if health is depleted
+	// Step 7: Burn energy for living and moving, starving if there is none
+	e.Metabolise(deltaTime)
+
+	// Step 8: Deactivate if health is depleted
 	if e.Health <= 0 {
-		e.Active = false
-		fmt.Printf("Entity %d has been deactivated due to depleted health.\n", e.ID)
+		e.SetActive(false)
+		e.world.logf("Entity %d has been deactivated due to depleted health.\n", e.ID)
 	}
 
 }
@@ -250,11 +360,15 @@ func (e *Entity) Consume(nearbyEntities []*Entity) {
 		}
 
 		// Step 2: Check if the other entity is close enough to be consumed
-		dx := other.X - e.X
-		dy := other.Y - e.Y
+		ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+		dx := ox - e.X
+		dy := oy - e.Y
 		distanceSquared := dx*dx + dy*dy
-		entityRadius := e.Width
-		consumptionRange := 1.5 * entityRadius
+		consumptionRange := 1.5 * e.Width
+		if e.world.config.Collisions {
+			// Solid entities never overlap, so touching is close enough
+			consumptionRange = math.Max(consumptionRange, e.Width+other.Width+contactReach)
+		}
 		consumptionThreshold := consumptionRange * consumptionRange
 
 		if distanceSquared > consumptionThreshold {
@@ -267,15 +381,23 @@ func (e *Entity) Consume(nearbyEntities []*Entity) {
 		// Heal entity for eating
 		// Damage other for being consumed
 		e.Health -= healthPenalty * 0.3
+		wasAlive := other.Health > 0
 		other.Health -= healthPenalty
+		if wasAlive && other.Health <= 0 {
+			e.world.stats.Kills[e.TeamID]++
+			e.kills++
+		}
 
 		// If health drops below zero, deactivate the entity
 		if e.Health <= 0 {
-			e.Active = false
-			fmt.Printf("Entity %d has died after consuming Entity %d.\n", e.ID, other.ID)
+			e.SetActive(false)
+			e.world.logf("Entity %d has died after consuming Entity %d.\n", e.ID, other.ID)
 			return // Stop processing further consumption for this entity
 		}
 
+		// Attacking is tiring
+		e.Spend(e.world.config.attackCost())
+
 		// Increase the size of the consuming entity
 		e.Grow(0.1)
 
@@ -284,14 +406,64 @@ func (e *Entity) Consume(nearbyEntities []*Entity) {
 		other.InvulnTimer = 1.0 + (e.Width / 200.0) // Set invulnerability duration based on size
 
 		// Logging for debugging
-		fmt.Printf("Entity %d (Team %d) consumed Entity %d (Team %d). Healing %f, New size: (%.2f, %.2f). Health: %.2f\n", e.ID, e.TeamID, other.ID, other.TeamID, healthPenalty, e.Width, e.Height, e.Health)
-		fmt.Printf("Entity %d (Team %d) consumed by Entity %d (Team %d). Taking %.2f damage. Health: %.2f\n", other.ID, other.TeamID, e.ID, e.TeamID, healthPenalty, e.Health)
+		e.world.logf("Entity %d (Team %d) consumed Entity %d (Team %d). Healing %f, New size: (%.2f, %.2f). Health: %.2f\n", e.ID, e.TeamID, other.ID, other.TeamID, healthPenalty, e.Width, e.Height, e.Health)
+		e.world.logf("Entity %d (Team %d) consumed by Entity %d (Team %d). Taking %.2f damage. Health: %.2f\n", other.ID, other.TeamID, e.ID, e.TeamID, healthPenalty, e.Health)
+	}
+}
+
+// steerTowards returns a unit-speed nudge of the velocity towards a point,
+// following a planned path when an obstacle is in the way.
+func (e *Entity) steerTowards(x, y float64) (float64, float64) {
+	w := e.world
+	x, y = w.unwrap(e.X, e.Y, x, y)
+	if w.nav != nil && !w.lineOfSight(e.X, e.Y, x, y) {
+		if w.config.Wrap {
+			// Planned paths do not cross the edges of a wrapping arena,
+			// so head around the obstacle in the way instead
+			x, y = e.detour(x, y)
+		} else {
+			x, y = e.waypoint(x, y)
+		}
+	}
+	dx := x - e.X
+	dy := y - e.Y
+	length := math.Sqrt(dx*dx + dy*dy)
+	if length == 0 {
+		return 0, 0
+	}
+	return (dx / length) * 0.1, (dy / length) * 0.1
+}
+
+// wander returns a nudge in a direction that changes about once a second.
+// The direction is hashed from the entity and the tick rather than drawn
+// from the world's random source, so it is safe to call while deciding.
+func (e *Entity) wander(tick int) (float64, float64) {
+	h := uint64(e.ID)*0x9E3779B97F4A7C15 ^ uint64(tick/60)*0xBF58476D1CE4E5B9
+	h ^= h >> 31
+	h *= 0x94D049BB133111EB
+	h ^= h >> 29
+	angle := float64(h%3600) / 3600 * 2 * math.Pi
+	return math.Cos(angle) * 0.1, math.Sin(angle) * 0.1
+}
+
+// consumeReach is the furthest away another entity e can consume may be.
+func (e *Entity) consumeReach() float64 {
+	if e.world.config.Collisions {
+		// Anything e can consume is smaller than it
+		return 2*e.Width + contactReach
 	}
+	return 1.5 * e.Width
 }
 
-func (e *Entity) SeekFood(nearbyFood []*Food) {
+// foodReach is the furthest away any food e can eat may be.
+func (e *Entity) foodReach() float64 {
+	return (maxFoodSize + e.Width) * 1.2
+}
+
+// SeekFood returns the steering towards the closest active food.
+func (e *Entity) SeekFood(nearbyFood []*Food) (float64, float64) {
 	if !e.Active {
-		return // Skip if the entity is not active
+		return 0, 0 // Skip if the entity is not active
 	}
 
 	// Find the closest active food
@@ -305,8 +477,9 @@ func (e *Entity) SeekFood(nearbyFood []*Food) {
 		}
 
 		// Calculate squared distance to the food
-		dx := food.X - e.X
-		dy := food.Y - e.Y
+		fx, fy := e.world.unwrap(e.X, e.Y, food.X, food.Y)
+		dx := fx - e.X
+		dy := fy - e.Y
 		distanceSquared := dx*dx + dy*dy
 
 		// Check if this is the closest food found so far
@@ -318,17 +491,10 @@ func (e *Entity) SeekFood(nearbyFood []*Food) {
 
 	// Move towards the closest food if found
 	if closestFood != nil {
-		dx := closestFood.X - e.X
-		dy := closestFood.Y - e.Y
-
-		// Normalize the direction and adjust velocity using pythagoras
-		length := math.Sqrt(dx*dx + dy*dy)
-		if length != 0 {
-			// TODO: Scale on hunger need?
-			e.VX += (dx / length) * 0.1 // Scale speed towards the food
-			e.VY += (dy / length) * 0.1
-		}
+		// TODO: Scale on hunger need?
+		return e.steerTowards(closestFood.X, closestFood.Y)
 	}
+	return 0, 0
 }
 
 func (e *Entity) ConsumeFood(nearbyFood []*Food) {
@@ -343,8 +509,9 @@ func (e *Entity) ConsumeFood(nearbyFood []*Food) {
 		}
 
 		// Check if the entity is close enough to consume the food
-		dx := food.X - e.X
-		dy := food.Y - e.Y
+		fx, fy := e.world.unwrap(e.X, e.Y, food.X, food.Y)
+		dx := fx - e.X
+		dy := fy - e.Y
 		distanceSquared := dx*dx + dy*dy
 		foodThreshold := (food.Size + e.Width) * 1.2 // Consumption range based on entity size
 
@@ -353,30 +520,38 @@ func (e *Entity) ConsumeFood(nearbyFood []*Food) {
 			e.Grow(0.1)
 			e.Health += food.Size * 2
 			food.Active = false // Deactivate the food
+			e.world.stats.FoodEaten[e.TeamID]++
+			e.foodEaten++
 
-			fmt.Printf("Entity %d consumed Food %d and grew.\n", e.ID, food.ID)
-			e.HungerLevel = 0.0
+			e.world.logf("Entity %d consumed Food %d and grew.\n", e.ID, food.ID)
+			e.Feed(food.Size * e.world.config.foodEnergy())
 			break // Only consume one food per update
 		}
 	}
 }
+
 func (e *Entity) SetActive(active bool) {
+	if e.Active && !active && e.world != nil {
+		e.diedAt = e.world.ticks // Remember when the entity died for survival stats
+	}
 	e.Active = active
 }
 
 func (e *Entity) Grow(factor float64) {
-	if e.Width < config.MaxSize {
+	if e.Width < e.world.config.MaxSize {
 		e.Width += e.Width * factor
 	}
 }
 
-func (e *Entity) AssistTeamMember(entities []*Entity) {
+// AssistTeamMember returns the nearest teammate in need of help within
+// reach, or nil.
+func (e *Entity) AssistTeamMember(entities []*Entity) *Entity {
 	// Find the nearest teammate in need of help
 	var nearestTeammate *Entity
 	minDistance := 4.0
 
 	for _, teammate := range entities {
-		if teammate.TeamID == e.TeamID && teammate != e && teammate.Health < 50 {
+		if teammate.TeamID == e.TeamID && teammate != e && teammate.Health < injuredHealth {
 			// Calculate the distance to the teammate
 			distance := e.DistanceTo(teammate)
 
@@ -388,18 +563,45 @@ func (e *Entity) AssistTeamMember(entities []*Entity) {
 		}
 	}
 
-	// If a teammate is found, perform an assist action
-	if nearestTeammate != nil {
-		e.PerformAssistAction(nearestTeammate)
-		e.TeamTimeout = true
-		e.TeamAssistTimeout = 5.0
+	return nearestTeammate
+}
+
+// Kills returns how many enemies the entity has reduced to zero health.
+func (e *Entity) Kills() int {
+	return e.kills
+}
+
+// FoodEaten returns how many food items the entity has eaten.
+func (e *Entity) FoodEaten() int {
+	return e.foodEaten
+}
+
+// SurvivalTicks returns how many ticks the entity has been alive for.
+func (e *Entity) SurvivalTicks() int {
+	if !e.Active {
+		return e.diedAt - e.bornAt
 	}
+	return e.world.ticks - e.bornAt
+}
+
+// Fitness scores how well the entity did: seconds survived plus kills plus
+// food eaten.
+func (e *Entity) Fitness() float64 {
+	return float64(e.SurvivalTicks())*e.world.TickDuration() + float64(e.kills) + float64(e.foodEaten)
+}
+
+// distance between two points
+func distance(x1, y1, x2, y2 float64) float64 {
+	dx := x1 - x2
+	dy := y1 - y2
+	return math.Sqrt(dx*dx + dy*dy)
 }
 
 // Calculate distance between two entities (helper method)
 func (e *Entity) DistanceTo(other *Entity) float64 {
-	dx := e.X - other.X
-	dy := e.Y - other.Y
+	ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+	dx := e.X - ox
+	dy := e.Y - oy
 	return math.Sqrt(dx*dx + dy*dy)
 }
 
@@ -415,5 +617,5 @@ func (e *Entity) PerformAssistAction(teammate *Entity) {
 	}
 
 	// Optionally, you could also print a log or message
-	fmt.Printf("Entity %d assisted teammate %d, healing them by %f.\n", e.ID, teammate.ID, healAmount)
+	e.world.logf("Entity %d assisted teammate %d, healing them by %f.\n", e.ID, teammate.ID, healAmount)
 }
@@ -0,0 +1,255 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Flocking is how a team's members move together. The boids rules keep
+// teammates apart (Separation), heading the same way (Alignment) and
+// together (Cohesion), and are blended into whatever the brain decided. A
+// Formation of line, wedge or circle also steers each member towards its
+// place around the team's leader, the member with the lowest ID.
+type Flocking struct {
+	Separation, Alignment, Cohesion float64 // Weights of the boids rules
+	// Radius is how far away teammates count as neighbours. Zero uses 50.
+	Radius float64
+	// Formation is "", "line", "wedge" or "circle".
+	Formation string
+	// FormationWeight weighs steering into place. Zero uses 1.
+	FormationWeight float64
+	// Spacing is the distance between places in the formation. Zero uses 30.
+	Spacing float64
+}
+
+const (
+	defaultFlockRadius      = 50.0
+	defaultFormationSpacing = 30.0
+	formationGain           = 0.5 // Speed towards a place per unit of distance from it
+)
+
+var formations = map[string]bool{"line": true, "wedge": true, "circle": true}
+
+// ParseFlocking reads a flocking spec of the form kind[:sep/align/coh],
+// where kind is flock for the boids rules alone, the name of a formation,
+// or none. The weights default to 1.5/1/1.
+func ParseFlocking(spec string) (Flocking, error) {
+	kind, weights, _ := strings.Cut(spec, ":")
+	f := Flocking{Separation: 1.5, Alignment: 1, Cohesion: 1}
+	switch {
+	case kind == "" || kind == "none":
+		return Flocking{}, nil
+	case kind == "flock":
+	case formations[kind]:
+		f.Formation = kind
+	default:
+		return Flocking{}, fmt.Errorf("unknown flocking %q", kind)
+	}
+	if weights == "" {
+		return f, nil
+	}
+	parts := strings.Split(weights, "/")
+	if len(parts) != 3 {
+		return Flocking{}, fmt.Errorf("flocking %q needs separation/alignment/cohesion weights", spec)
+	}
+	values := make([]float64, 3)
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil {
+			return Flocking{}, fmt.Errorf("flocking %q: %w", spec, err)
+		}
+		values[i] = v
+	}
+	f.Separation, f.Alignment, f.Cohesion = values[0], values[1], values[2]
+	return f, nil
+}
+
+// ParseTeamFlocking reads a comma separated flocking spec per team.
+func ParseTeamFlocking(specs string) ([]Flocking, error) {
+	var teams []Flocking
+	for team, spec := range strings.Split(specs, ",") {
+		f, err := ParseFlocking(spec)
+		if err != nil {
+			return nil, fmt.Errorf("team %d: %w", team, err)
+		}
+		teams = append(teams, f)
+	}
+	return teams, nil
+}
+
+// flockingFor returns a team's flocking, if it has any.
+func (c Config) flockingFor(team int) (Flocking, bool) {
+	if team < 0 || team >= len(c.Flocking) {
+		return Flocking{}, false
+	}
+	f := c.Flocking[team]
+	return f, f.Separation != 0 || f.Alignment != 0 || f.Cohesion != 0 || f.Formation != ""
+}
+
+// normalised scales a steering direction to the usual steering strength.
+func normalised(x, y float64) (float64, float64) {
+	length := math.Sqrt(x*x + y*y)
+	if length == 0 {
+		return 0, 0
+	}
+	return x / length * 0.1, y / length * 0.1
+}
+
+// Separation steers away from neighbours, harder from closer ones.
+func (e *Entity) Separation(neighbours []*Entity) (float64, float64) {
+	var x, y float64
+	for _, other := range neighbours {
+		ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+		dx := e.X - ox
+		dy := e.Y - oy
+		d2 := dx*dx + dy*dy
+		if other == e || d2 == 0 {
+			continue
+		}
+		x += dx / d2
+		y += dy / d2
+	}
+	return normalised(x, y)
+}
+
+// Alignment steers towards the neighbours' average velocity.
+func (e *Entity) Alignment(neighbours []*Entity) (float64, float64) {
+	var vx, vy float64
+	n := 0
+	for _, other := range neighbours {
+		if other != e {
+			vx += other.VX
+			vy += other.VY
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return normalised(vx/float64(n)-e.VX, vy/float64(n)-e.VY)
+}
+
+// Cohesion steers towards the neighbours' centre.
+func (e *Entity) Cohesion(neighbours []*Entity) (float64, float64) {
+	var x, y float64
+	n := 0
+	for _, other := range neighbours {
+		if other != e {
+			ox, oy := e.world.unwrap(e.X, e.Y, other.X, other.Y)
+			x += ox
+			y += oy
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return normalised(x/float64(n)-e.X, y/float64(n)-e.Y)
+}
+
+// flock adds the team's flocking to a decision. It runs while deciding,
+// after the brain.
+func (p *Perception) flock(f Flocking, d *Decision) {
+	e := p.Self
+	w := p.view.world
+
+	if f.Separation != 0 || f.Alignment != 0 || f.Cohesion != 0 {
+		neighbours := p.view.teammates[:0]
+		for _, other := range p.EntitiesWithin(orDefault(f.Radius, defaultFlockRadius)) {
+			if other.TeamID == e.TeamID && other != e {
+				neighbours = append(neighbours, other)
+			}
+		}
+		p.view.teammates = neighbours
+
+		sx, sy := e.Separation(neighbours)
+		ax, ay := e.Alignment(neighbours)
+		cx, cy := e.Cohesion(neighbours)
+		d.SteerX += f.Separation*sx + f.Alignment*ax + f.Cohesion*cx
+		d.SteerY += f.Separation*sy + f.Alignment*ay + f.Cohesion*cy
+	}
+
+	if f.Formation != "" && e.TeamID < len(w.leaders) {
+		leader := w.leaders[e.TeamID]
+		if leader != nil && leader != e {
+			// Match the leader's velocity plus whatever closes the gap, so
+			// members settle into place instead of circling it
+			x, y := formationPlace(f, leader, e.formationSlot, w.teamSizes[e.TeamID]-1)
+			x, y = w.unwrap(e.X, e.Y, x, y)
+			fx, fy := normalised(leader.VX+(x-e.X)*formationGain-e.VX, leader.VY+(y-e.Y)*formationGain-e.VY)
+			weight := orDefault(f.FormationWeight, 1)
+			d.SteerX += weight * fx
+			d.SteerY += weight * fy
+		}
+	}
+}
+
+// formationPlace returns where follower slot of followers belongs around
+// the leader, laid out relative to the leader's heading.
+func formationPlace(f Flocking, leader *Entity, slot, followers int) (float64, float64) {
+	spacing := orDefault(f.Spacing, defaultFormationSpacing)
+	// Forward and right of the leader
+	fx, fy := math.Cos(leader.Heading), math.Sin(leader.Heading)
+	rx, ry := -fy, fx
+
+	var back, side float64
+	switch f.Formation {
+	case "line":
+		// Abreast, alternating right and left of the leader
+		rank := float64(slot/2 + 1)
+		side = rank * spacing
+		if slot%2 == 1 {
+			side = -side
+		}
+	case "wedge":
+		// A V trailing behind the leader
+		rank := float64(slot/2 + 1)
+		back = rank * spacing
+		side = rank * spacing
+		if slot%2 == 1 {
+			side = -side
+		}
+	case "circle":
+		// Evenly around the leader, far enough out to keep spacing apart
+		radius := math.Max(spacing, spacing*float64(followers)/(2*math.Pi))
+		angle := 2 * math.Pi * float64(slot) / float64(max(1, followers))
+		return leader.X + radius*math.Cos(leader.Heading+angle), leader.Y + radius*math.Sin(leader.Heading+angle)
+	}
+	return leader.X - back*fx + side*rx, leader.Y - back*fy + side*ry
+}
+
+// assignFormations picks each team's leader and numbers its followers in
+// ID order. It runs before entities decide.
+func (w *World) assignFormations() {
+	if len(w.config.Flocking) == 0 {
+		return
+	}
+	if len(w.leaders) != w.teams {
+		w.leaders = make([]*Entity, w.teams)
+		w.teamSizes = make([]int, w.teams)
+	}
+	for team := range w.leaders {
+		w.leaders[team] = nil
+		w.teamSizes[team] = 0
+	}
+	for _, e := range w.entities {
+		if !e.Active || e.TeamID < 0 || e.TeamID >= w.teams {
+			continue
+		}
+		if leader := w.leaders[e.TeamID]; leader == nil || e.ID < leader.ID {
+			w.leaders[e.TeamID] = e
+		}
+		w.teamSizes[e.TeamID]++
+	}
+	// Entities are appended in ID order, so slots follow slice order
+	slots := make([]int, w.teams)
+	for _, e := range w.entities {
+		if !e.Active || e.TeamID < 0 || e.TeamID >= w.teams || e == w.leaders[e.TeamID] {
+			continue
+		}
+		e.formationSlot = slots[e.TeamID]
+		slots[e.TeamID]++
+	}
+}
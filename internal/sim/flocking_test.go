@@ -0,0 +1,48 @@
+package sim
+
+import "testing"
+
+func TestParseFlocking(t *testing.T) {
+	tests := []struct {
+		spec string
+		want Flocking
+	}{
+		{"", Flocking{}},
+		{"none", Flocking{}},
+		{"none:1/2/3", Flocking{}},
+		{"flock", Flocking{Separation: 1.5, Alignment: 1, Cohesion: 1}},
+		{"flock:", Flocking{Separation: 1.5, Alignment: 1, Cohesion: 1}},
+		{"flock:2/0.5/0", Flocking{Separation: 2, Alignment: 0.5, Cohesion: 0}},
+		{"wedge", Flocking{Separation: 1.5, Alignment: 1, Cohesion: 1, Formation: "wedge"}},
+		{"circle:0/0/0", Flocking{Formation: "circle"}},
+	}
+	for _, test := range tests {
+		got, err := ParseFlocking(test.spec)
+		if err != nil {
+			t.Errorf("%q: unexpected error %v", test.spec, err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%q: got %+v, want %+v", test.spec, got, test.want)
+		}
+	}
+
+	for _, spec := range []string{"swarm", "flock:1/2", "flock:1/2/3/4", "line:a/b/c"} {
+		if _, err := ParseFlocking(spec); err == nil {
+			t.Errorf("%q: expected an error", spec)
+		}
+	}
+}
+
+func TestParseTeamFlocking(t *testing.T) {
+	teams, err := ParseTeamFlocking("flock,,line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(teams) != 3 || teams[1] != (Flocking{}) || teams[2].Formation != "line" {
+		t.Errorf("got %+v", teams)
+	}
+	if _, err := ParseTeamFlocking("flock,swarm"); err == nil {
+		t.Error("expected an error for an unknown flocking")
+	}
+}
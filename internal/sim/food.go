@@ -1,9 +1,6 @@
 package sim
 
-import (
-	"fmt"
-	"math/rand"
-)
+const minFoodSize, maxFoodSize = 2.0, 5.0
 
 type Food struct {
 	ID     int     // Unique identifier for the food
@@ -12,31 +9,33 @@ type Food struct {
 	Active bool    // Whether the food is still available
 }
 
-func InitializeFood(count int, canvasWidth, canvasHeight float64) {
-	foods = make([]*Food, count)
+func (w *World) InitializeFood(count int) {
+	w.foods = make([]*Food, count)
 
 	for i := 0; i < count; i++ {
-		foods[i] = &Food{
+		x, y := w.openPoint(maxFoodSize)
+		w.foods[i] = &Food{
 			ID:     i + 1,
-			X:      randFloat(0, canvasWidth),
-			Y:      randFloat(0, canvasHeight),
-			Size:   randFloat(2, 5), // Random size for the food items
+			X:      x,
+			Y:      y,
+			Size:   w.randFloat(minFoodSize, maxFoodSize), // Random size for the food items
 			Active: true,
 		}
 	}
 }
 
-func RespawnFood(chance float64, canvasWidth, canvasHeight float64) {
-	for i := range foods {
-		if !foods[i].Active && rand.Float64() < chance {
-			foods[i] = &Food{
-				ID:     foods[i].ID,
-				X:      randFloat(0, canvasWidth),
-				Y:      randFloat(0, canvasHeight),
-				Size:   randFloat(2, 5),
+func (w *World) RespawnFood(chance float64) {
+	for i := range w.foods {
+		if !w.foods[i].Active && w.rng.Float64() < chance {
+			x, y := w.openPoint(maxFoodSize)
+			w.foods[i] = &Food{
+				ID:     w.foods[i].ID,
+				X:      x,
+				Y:      y,
+				Size:   w.randFloat(minFoodSize, maxFoodSize),
 				Active: true,
 			}
-			fmt.Printf("Food %d respawned.\n", foods[i].ID)
+			w.logf("Food %d respawned.\n", w.foods[i].ID)
 		}
 	}
 }
@@ -0,0 +1,115 @@
+package sim
+
+import (
+	"math"
+	"math/rand"
+)
+
+// Genome holds the heritable traits that shape an entity's behaviour.
+type Genome struct {
+	SpeedMultiplier  float64 // Scales the size-based top speed
+	PerceptionRadius float64 // Distance threats are noticed and fled from
+	HungerThreshold  float64 // Hunger level food becomes the priority at
+	Aggression       float64 // 0 to 1, higher stands its ground longer
+	AssistPropensity float64 // 0 to 1, higher answers teammates sooner
+	BirthSize        float64 // Width the entity starts with
+}
+
+// DefaultGenome returns the traits every entity had before genomes existed,
+// with a random birth size.
+func (w *World) DefaultGenome() Genome {
+	return Genome{
+		SpeedMultiplier:  1,
+		PerceptionRadius: w.config.fleeRadius(),
+		HungerThreshold:  80,
+		Aggression:       0.5,
+		AssistPropensity: 0.5,
+		BirthSize:        w.randFloat(w.config.MinSize, w.config.StartMaxSize),
+	}
+}
+
+// fleeThreshold is the flee urgency above which the entity runs.
+func (g Genome) fleeThreshold() float64 {
+	return 100 * g.Aggression
+}
+
+// assistThreshold is the team need above which the entity helps out.
+func (g Genome) assistThreshold() float64 {
+	return 100 * (1 - g.AssistPropensity)
+}
+
+// genes lists the genome's traits for crossover and mutation.
+func (g *Genome) genes() []*float64 {
+	return []*float64{
+		&g.SpeedMultiplier,
+		&g.PerceptionRadius,
+		&g.HungerThreshold,
+		&g.Aggression,
+		&g.AssistPropensity,
+		&g.BirthSize,
+	}
+}
+
+// GenomeBounds returns the lowest and highest value of each trait.
+func GenomeBounds(c Config) (Genome, Genome) {
+	return Genome{
+		SpeedMultiplier:  0.5,
+		PerceptionRadius: 20,
+		HungerThreshold:  0,
+		Aggression:       0,
+		AssistPropensity: 0,
+		BirthSize:        c.MinSize,
+	}, Genome{
+		SpeedMultiplier:  2,
+		PerceptionRadius: 300,
+		HungerThreshold:  150,
+		Aggression:       1,
+		AssistPropensity: 1,
+		BirthSize:        c.MaxSize,
+	}
+}
+
+// Crossover builds a child genome taking each trait from either parent at
+// random.
+func Crossover(a, b Genome, rng *rand.Rand) Genome {
+	child := a
+	childGenes, bGenes := child.genes(), b.genes()
+	for i := range childGenes {
+		if rng.Intn(2) == 1 {
+			*childGenes[i] = *bGenes[i]
+		}
+	}
+	return child
+}
+
+// Mutate perturbs each trait with probability rate by gaussian noise of
+// scale times the trait's range, keeping it within bounds.
+func Mutate(g Genome, c Config, rate, scale float64, rng *rand.Rand) Genome {
+	lo, hi := GenomeBounds(c)
+	genes, loGenes, hiGenes := g.genes(), lo.genes(), hi.genes()
+	for i, gene := range genes {
+		if rng.Float64() >= rate {
+			continue
+		}
+		span := *hiGenes[i] - *loGenes[i]
+		*gene = clamp(*gene+rng.NormFloat64()*scale*span, *loGenes[i], *hiGenes[i])
+	}
+	return g
+}
+
+// Distance returns how different two genomes are, as the root mean square
+// of their trait differences relative to each trait's range.
+func (g Genome) Distance(other Genome, c Config) float64 {
+	lo, hi := GenomeBounds(c)
+	genes, otherGenes, loGenes, hiGenes := g.genes(), other.genes(), lo.genes(), hi.genes()
+	var sum float64
+	for i := range genes {
+		span := *hiGenes[i] - *loGenes[i]
+		if span == 0 {
+			continue
+		}
+		d := (*genes[i] - *otherGenes[i]) / span
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(genes)))
+}
@@ -0,0 +1,341 @@
+package sim
+
+import "math"
+
+type gridItem struct {
+	index int // Index into the slice the grid was built from
+	x, y  float64
+}
+
+// spatialGrid buckets positions into uniform square cells so neighbour
+// queries only visit the cells around the query point instead of every
+// item in the world. It is rebuilt from scratch each tick.
+type spatialGrid struct {
+	cellSize      float64
+	cols, rows    int
+	cells         [][]gridItem
+	wrap          bool    // Queries reach across the edges
+	width, height float64 // Area covered, for wrapping
+}
+
+// reset empties the grid and sizes it to cover a width x height area,
+// reusing the previous cell storage where it can. With wrap set, queries
+// near one edge also find items near the opposite one.
+func (g *spatialGrid) reset(width, height, cellSize float64, wrap bool) {
+	cols := int(math.Max(1, math.Ceil(width/cellSize)))
+	rows := int(math.Max(1, math.Ceil(height/cellSize)))
+	g.cellSize = cellSize
+	g.wrap, g.width, g.height = wrap, width, height
+	if cols*rows != len(g.cells) {
+		g.cells = make([][]gridItem, cols*rows)
+	}
+	g.cols, g.rows = cols, rows
+	for i := range g.cells {
+		g.cells[i] = g.cells[i][:0]
+	}
+}
+
+// cellOf returns the cell containing a point. Points outside the grid are
+// clamped into the border cells.
+func (g *spatialGrid) cellOf(x, y float64) (int, int) {
+	cx := int(math.Floor(x / g.cellSize))
+	cy := int(math.Floor(y / g.cellSize))
+	if cx < 0 {
+		cx = 0
+	} else if cx >= g.cols {
+		cx = g.cols - 1
+	}
+	if cy < 0 {
+		cy = 0
+	} else if cy >= g.rows {
+		cy = g.rows - 1
+	}
+	return cx, cy
+}
+
+func (g *spatialGrid) insert(index int, x, y float64) {
+	cx, cy := g.cellOf(x, y)
+	cell := cy*g.cols + cx
+	g.cells[cell] = append(g.cells[cell], gridItem{index: index, x: x, y: y})
+}
+
+// within appends to out the indices of every item within radius of (x, y).
+func (g *spatialGrid) within(x, y, radius float64, out []int) []int {
+	if g.wrap {
+		g.visitWrapped(x, y, radius, func(item gridItem, _ float64) {
+			out = append(out, item.index)
+		})
+		return out
+	}
+	minX, minY := g.cellOf(x-radius, y-radius)
+	maxX, maxY := g.cellOf(x+radius, y+radius)
+	radiusSquared := radius * radius
+	for cy := minY; cy <= maxY; cy++ {
+		for _, cell := range g.cells[cy*g.cols+minX : cy*g.cols+maxX+1] {
+			for _, item := range cell {
+				dx := item.x - x
+				dy := item.y - y
+				if dx*dx+dy*dy <= radiusSquared {
+					out = append(out, item.index)
+				}
+			}
+		}
+	}
+	return out
+}
+
+// nearest appends to out the indices of up to k accepted items closest to
+// (x, y) and no further than maxRadius, closest first. It searches outwards
+// ring by ring and stops once no unvisited cell can hold anything closer
+// than what it has found.
+func (g *spatialGrid) nearest(x, y float64, k int, maxRadius float64, accept func(index int) bool, out []int) []int {
+	if g.wrap {
+		return g.nearestWrapped(x, y, k, maxRadius, accept, out)
+	}
+	var best []candidate
+
+	cx, cy := g.cellOf(x, y)
+	scan := func(rx, ry int) {
+		for _, item := range g.cells[ry*g.cols+rx] {
+			if accept != nil && !accept(item.index) {
+				continue
+			}
+			dx := item.x - x
+			dy := item.y - y
+			d := dx*dx + dy*dy
+			if d > maxRadius*maxRadius {
+				continue
+			}
+			if len(best) == k && d >= best[k-1].distanceSquared {
+				continue
+			}
+			best = insertCandidate(best, k, item.index, d)
+		}
+	}
+
+	for ring := 0; ; ring++ {
+		// Visit only the cells on the edge of this ring that lie inside the grid
+		left, right := max(cx-ring, 0), min(cx+ring, g.cols-1)
+		top, bottom := max(cy-ring, 0), min(cy+ring, g.rows-1)
+		for ry := top; ry <= bottom; ry++ {
+			if ry == cy-ring || ry == cy+ring {
+				for rx := left; rx <= right; rx++ {
+					scan(rx, ry)
+				}
+				continue
+			}
+			if cx-ring >= 0 {
+				scan(cx-ring, ry)
+			}
+			if ring > 0 && cx+ring < g.cols {
+				scan(cx+ring, ry)
+			}
+		}
+
+		// Anything in a later ring is at least ring*cellSize away, and
+		// once the ring covers the grid there is nothing left to visit
+		reach := float64(ring) * g.cellSize
+		if len(best) == k && best[k-1].distanceSquared <= reach*reach || reach > maxRadius {
+			break
+		}
+		if cx-ring <= 0 && cy-ring <= 0 && cx+ring >= g.cols-1 && cy+ring >= g.rows-1 {
+			break
+		}
+	}
+
+	for _, c := range best {
+		out = append(out, c.index)
+	}
+	return out
+}
+
+type candidate struct {
+	index           int
+	distanceSquared float64
+}
+
+// insertCandidate adds a candidate to best in order of distance, dropping
+// the furthest once there are k.
+func insertCandidate(best []candidate, k, index int, distanceSquared float64) []candidate {
+	pos := len(best)
+	for pos > 0 && best[pos-1].distanceSquared > distanceSquared {
+		pos--
+	}
+	if len(best) < k {
+		best = append(best, candidate{})
+	}
+	copy(best[pos+1:], best[pos:len(best)-1])
+	best[pos] = candidate{index: index, distanceSquared: distanceSquared}
+	return best
+}
+
+// nearestWrapped is nearest for a grid whose edges wrap. It searches
+// within a radius that doubles until it holds k accepted items, since
+// anything it has not visited is further away than the radius.
+func (g *spatialGrid) nearestWrapped(x, y float64, k int, maxRadius float64, accept func(index int) bool, out []int) []int {
+	var best []candidate
+	furthest := math.Hypot(g.width, g.height) / 2 // Nothing is further away on a torus
+	for radius := g.cellSize; ; radius *= 2 {
+		radius = math.Min(radius, maxRadius)
+		best = best[:0]
+		g.visitWrapped(x, y, radius, func(item gridItem, d float64) {
+			if accept != nil && !accept(item.index) {
+				return
+			}
+			if len(best) == k && d >= best[k-1].distanceSquared {
+				return
+			}
+			best = insertCandidate(best, k, item.index, d)
+		})
+		if len(best) == k || radius >= maxRadius || radius >= furthest {
+			break
+		}
+	}
+
+	for _, c := range best {
+		out = append(out, c.index)
+	}
+	return out
+}
+
+// visitWrapped calls visit with every item within radius of (x, y) the
+// shortest way round a wrapping grid, and its squared distance.
+func (g *spatialGrid) visitWrapped(x, y, radius float64, visit func(item gridItem, distanceSquared float64)) {
+	cols, colSpans := g.spans(x-radius, x+radius, g.width, g.cols)
+	rows, rowSpans := g.spans(y-radius, y+radius, g.height, g.rows)
+	radiusSquared := radius * radius
+	for _, rowSpan := range rows[:rowSpans] {
+		for cy := rowSpan[0]; cy <= rowSpan[1]; cy++ {
+			for _, colSpan := range cols[:colSpans] {
+				for _, cell := range g.cells[cy*g.cols+colSpan[0] : cy*g.cols+colSpan[1]+1] {
+					for _, item := range cell {
+						dx := wrapDelta(item.x-x, g.width)
+						dy := wrapDelta(item.y-y, g.height)
+						if d := dx*dx + dy*dy; d <= radiusSquared {
+							visit(item, d)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+// spans returns the runs of cells, first to last, that cover lo to hi
+// along a wrapping axis of n cells spanning size. A range crossing the end
+// of the axis takes two runs.
+func (g *spatialGrid) spans(lo, hi, size float64, n int) ([2][2]int, int) {
+	if hi-lo >= size {
+		return [2][2]int{{0, n - 1}}, 1
+	}
+	cell := func(v float64) int {
+		return int(clamp(math.Floor(v/g.cellSize), 0, float64(n-1)))
+	}
+	length := hi - lo
+	lo = wrapCoordinate(lo, size)
+	hi = lo + length
+	if hi < size {
+		return [2][2]int{{cell(lo), cell(hi)}}, 1
+	}
+	first, last := cell(lo), cell(hi-size)
+	if last >= first {
+		// The two runs would meet, so take every cell once
+		return [2][2]int{{0, n - 1}}, 1
+	}
+	return [2][2]int{{first, n - 1}, {0, last}}, 2
+}
+
+// gridCellSize is the edge length of a grid cell. It is roughly half the
+// widest neighbour query so most radius queries touch a 3x3 to 5x5 block.
+const gridCellSize = 50.0
+
+// rebuildIndex re-buckets every active entity and food item. It runs once at
+// the start of each tick. Injured entities get a grid of their own because
+// team behaviours only ever look for them, and they are usually a small
+// fraction of the population.
+func (w *World) rebuildIndex() {
+	w.entityGrid.reset(w.canvasWidth, w.canvasHeight, gridCellSize, w.config.Wrap)
+	w.injuredGrid.reset(w.canvasWidth, w.canvasHeight, gridCellSize, w.config.Wrap)
+	for i, e := range w.entities {
+		if e.Active {
+			w.entityGrid.insert(i, e.X, e.Y)
+			if e.Health < injuredHealth {
+				w.injuredGrid.insert(i, e.X, e.Y)
+			}
+		}
+	}
+	w.foodGrid.reset(w.canvasWidth, w.canvasHeight, gridCellSize, w.config.Wrap)
+	for i, f := range w.foods {
+		if f.Active {
+			w.foodGrid.insert(i, f.X, f.Y)
+		}
+	}
+}
+
+// view answers neighbour queries against the index built at the start of
+// the tick. Its results reuse the view's buffers, so each goroutine needs a
+// view of its own and a result is only valid until the next query.
+type view struct {
+	world     *World
+	indices   []int
+	entities  []*Entity
+	foods     []*Food
+	memories  []Memory
+	teammates []*Entity
+}
+
+// entitiesWithin returns the entities within radius of a point.
+func (v *view) entitiesWithin(x, y, radius float64) []*Entity {
+	v.indices = v.world.entityGrid.within(x, y, radius, v.indices[:0])
+	v.entities = v.entities[:0]
+	for _, i := range v.indices {
+		v.entities = append(v.entities, v.world.entities[i])
+	}
+	return v.entities
+}
+
+// injuredWithin returns the injured entities within radius of a point.
+func (v *view) injuredWithin(x, y, radius float64) []*Entity {
+	v.indices = v.world.injuredGrid.within(x, y, radius, v.indices[:0])
+	v.entities = v.entities[:0]
+	for _, i := range v.indices {
+		v.entities = append(v.entities, v.world.entities[i])
+	}
+	return v.entities
+}
+
+// nearestEntity returns the closest entity within maxRadius accepted by
+// accept, or nil.
+func (v *view) nearestEntity(x, y, maxRadius float64, accept func(other *Entity) bool) *Entity {
+	entities := v.world.entities
+	v.indices = v.world.entityGrid.nearest(x, y, 1, maxRadius, func(i int) bool {
+		return accept(entities[i])
+	}, v.indices[:0])
+	if len(v.indices) == 0 {
+		return nil
+	}
+	return entities[v.indices[0]]
+}
+
+// foodWithin returns the food within radius of a point.
+func (v *view) foodWithin(x, y, radius float64) []*Food {
+	v.indices = v.world.foodGrid.within(x, y, radius, v.indices[:0])
+	v.foods = v.foods[:0]
+	for _, i := range v.indices {
+		v.foods = append(v.foods, v.world.foods[i])
+	}
+	return v.foods
+}
+
+// nearestFood returns the closest food within maxRadius accepted by
+// accept, or nil.
+func (v *view) nearestFood(x, y, maxRadius float64, accept func(f *Food) bool) *Food {
+	foods := v.world.foods
+	v.indices = v.world.foodGrid.nearest(x, y, 1, maxRadius, func(i int) bool {
+		return accept(foods[i])
+	}, v.indices[:0])
+	if len(v.indices) == 0 {
+		return nil
+	}
+	return foods[v.indices[0]]
+}
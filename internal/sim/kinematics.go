@@ -0,0 +1,72 @@
+package sim
+
+import "math"
+
+// With Config.Kinematics set, a decision's steering is a desired
+// acceleration rather than a change in velocity. Heavier entities answer
+// it more slowly, drag bleeds speed away, the speed cap applies to the
+// whole velocity rather than each axis, and entities can only turn so fast.
+
+const (
+	steerStrength  = 0.1  // Length of the steering behaviours return at full effort
+	referenceWidth = 10.0 // Width of an entity that gets the configured acceleration
+
+	defaultAcceleration = 20.0  // Pixels per second squared at full effort
+	defaultDrag         = 0.5   // Fraction of velocity lost per second
+	defaultTurnRate     = 360.0 // Degrees per second
+)
+
+// maxSpeed is the fastest the entity can move. Speed decreases as size
+// increases.
+func (e *Entity) maxSpeed() float64 {
+	sizeFactor := 1.0 / (1.0 + (e.Width / 100.0))
+	return e.world.config.BaseSpeed * sizeFactor * e.Genome.SpeedMultiplier
+}
+
+// accelerate sets the acceleration the entity will move with from a
+// steering decision. Steering beyond full effort is capped, and the
+// acceleration falls with the entity's mass.
+func (e *Entity) accelerate(steerX, steerY float64) {
+	effortX, effortY := steerX/steerStrength, steerY/steerStrength
+	if effort := math.Sqrt(effortX*effortX + effortY*effortY); effort > 1 {
+		effortX, effortY = effortX/effort, effortY/effort
+	}
+	c := e.world.config
+	acceleration := orDefault(c.Acceleration, defaultAcceleration) * referenceWidth * referenceWidth / e.mass()
+	e.ax, e.ay = effortX*acceleration, effortY*acceleration
+}
+
+// integrate advances the entity's velocity and heading by one tick.
+func (e *Entity) integrate(deltaTime float64) {
+	c := e.world.config
+
+	// The way it was going, or facing if it was still
+	from := e.Heading
+	if e.VX != 0 || e.VY != 0 {
+		from = math.Atan2(e.VY, e.VX)
+	}
+
+	e.VX += e.ax * deltaTime
+	e.VY += e.ay * deltaTime
+	decay := math.Max(0, 1-orDefaultIfNegative(c.Drag, defaultDrag)*deltaTime)
+	e.VX *= decay
+	e.VY *= decay
+
+	speed := math.Sqrt(e.VX*e.VX + e.VY*e.VY)
+	if speed == 0 {
+		return
+	}
+
+	// Turn no faster than the turn rate allows
+	heading := math.Atan2(e.VY, e.VX)
+	if turnRate := orDefaultIfNegative(c.TurnRate, defaultTurnRate); turnRate > 0 {
+		maxTurn := turnRate * math.Pi / 180 * deltaTime
+		turn := math.Remainder(heading-from, 2*math.Pi)
+		heading = from + clamp(turn, -maxTurn, maxTurn)
+	}
+	e.Heading = math.Remainder(heading, 2*math.Pi)
+
+	speed = math.Min(speed, e.maxSpeed())
+	e.VX = speed * math.Cos(heading)
+	e.VY = speed * math.Sin(heading)
+}
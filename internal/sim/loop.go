@@ -0,0 +1,53 @@
+package sim
+
+const (
+	defaultTickRate        = 60.0 // Simulation ticks per second
+	defaultMaxCatchUpTicks = 5    // Ticks allowed per Advance before time is dropped
+)
+
+// TickDuration returns the fixed simulated time that passes in one tick.
+func (w *World) TickDuration() float64 {
+	rate := w.config.TickRate
+	if rate <= 0 {
+		rate = defaultTickRate
+	}
+	return 1.0 / rate
+}
+
+// Step advances the world by exactly one fixed tick.
+func (w *World) Step() {
+	w.UpdateSimulation(w.TickDuration())
+	w.ticks++
+}
+
+// Ticks returns the number of fixed ticks run since the world was last
+// initialised.
+func (w *World) Ticks() int {
+	return w.ticks
+}
+
+// Advance feeds elapsed wall-clock seconds into the world's accumulator and
+// runs as many fixed ticks as are due. The outcome of a run depends only on
+// the number of ticks, never on how the wall-clock time was sliced. If the
+// caller falls behind by more than MaxCatchUpTicks, the backlog is dropped
+// rather than letting the simulation spiral. It returns the ticks run.
+func (w *World) Advance(elapsed float64) int {
+	maxTicks := w.config.MaxCatchUpTicks
+	if maxTicks <= 0 {
+		maxTicks = defaultMaxCatchUpTicks
+	}
+
+	step := w.TickDuration()
+	w.accumulator += elapsed
+
+	ticks := 0
+	for w.accumulator >= step && ticks < maxTicks {
+		w.Step()
+		w.accumulator -= step
+		ticks++
+	}
+	if ticks == maxTicks && w.accumulator >= step {
+		w.accumulator = 0 // Too far behind, drop the backlog
+	}
+	return ticks
+}
@@ -0,0 +1,166 @@
+package sim
+
+import "math"
+
+// MemoryKind is what an entity remembers seeing.
+type MemoryKind int
+
+const (
+	FoodMemory    MemoryKind = iota // Food
+	EnemyMemory                     // An entity on another team
+	InjuredMemory                   // An injured teammate
+)
+
+// maxMemories caps how much an entity remembers. Once full, the oldest
+// memory makes way for the new one.
+const maxMemories = 16
+
+// Memory is where an entity last saw something.
+type Memory struct {
+	Kind MemoryKind
+	ID   int // ID of the food or entity seen
+	X, Y float64
+	Size float64 // Food size or entity width when seen
+	Tick int     // Tick it was last seen
+
+	// Confidence falls from 1 when just seen to 0 when forgotten. It is
+	// filled in by Recall.
+	Confidence float64
+}
+
+// memorySpan returns how many ticks memories last, zero when entities have
+// no memory.
+func (w *World) memorySpan() int {
+	if w.config.MemorySpan <= 0 {
+		return 0
+	}
+	return int(math.Ceil(w.config.MemorySpan / w.TickDuration()))
+}
+
+// observe refreshes Self's memory before it decides. It forgets what is too
+// old, records what Self sees around it, and forgets anything it expected
+// to see in that area that is no longer there. It writes only to Self, so
+// entities can observe concurrently.
+func (p *Perception) observe() {
+	w := p.view.world
+	span := w.memorySpan()
+	e := p.Self
+
+	// Forget what has faded
+	kept := e.memories[:0]
+	for _, m := range e.memories {
+		if w.ticks-m.Tick < span {
+			kept = append(kept, m)
+		}
+	}
+	e.memories = kept
+
+	radius := math.Min(e.Genome.PerceptionRadius, w.config.viewDistance())
+	for _, other := range p.EntitiesWithin(radius) {
+		if other == e {
+			continue
+		}
+		if other.TeamID != e.TeamID {
+			e.remember(EnemyMemory, other.ID, other.X, other.Y, other.Width, w.ticks)
+		} else if other.Health < injuredHealth {
+			e.remember(InjuredMemory, other.ID, other.X, other.Y, other.Width, w.ticks)
+		}
+	}
+	for _, f := range p.FoodWithin(radius) {
+		e.remember(FoodMemory, f.ID, f.X, f.Y, f.Size, w.ticks)
+	}
+	if f := p.NearestFood(); f != nil {
+		e.remember(FoodMemory, f.ID, f.X, f.Y, f.Size, w.ticks)
+	}
+
+	// What should have been seen again but was not has gone
+	kept = e.memories[:0]
+	for _, m := range e.memories {
+		if m.Tick == w.ticks || w.distance(e.X, e.Y, m.X, m.Y) > radius || !p.sees(m.X, m.Y) {
+			kept = append(kept, m)
+		}
+	}
+	e.memories = kept
+}
+
+// remember records or refreshes a sighting.
+func (e *Entity) remember(kind MemoryKind, id int, x, y, size float64, tick int) {
+	m := Memory{Kind: kind, ID: id, X: x, Y: y, Size: size, Tick: tick}
+	oldest := 0
+	for i := range e.memories {
+		if e.memories[i].Kind == kind && e.memories[i].ID == id {
+			e.memories[i] = m
+			return
+		}
+		if e.memories[i].Tick < e.memories[oldest].Tick {
+			oldest = i
+		}
+	}
+	if len(e.memories) < maxMemories {
+		e.memories = append(e.memories, m)
+		return
+	}
+	e.memories[oldest] = m
+}
+
+// Recall returns what Self remembers of a kind, most recent first. The
+// slice is only valid until the next call.
+func (p *Perception) Recall(kind MemoryKind) []Memory {
+	w := p.view.world
+	span := float64(w.memorySpan())
+	p.view.memories = p.view.memories[:0]
+	for _, m := range p.Self.memories {
+		if m.Kind == kind {
+			m.Confidence = 1 - float64(w.ticks-m.Tick)/span
+			p.view.memories = append(p.view.memories, m)
+		}
+	}
+	recalled := p.view.memories
+	for i := 1; i < len(recalled); i++ {
+		for j := i; j > 0 && recalled[j].Tick > recalled[j-1].Tick; j-- {
+			recalled[j], recalled[j-1] = recalled[j-1], recalled[j]
+		}
+	}
+	return recalled
+}
+
+// RecallNearest returns the closest remembered thing of a kind.
+func (p *Perception) RecallNearest(kind MemoryKind) (Memory, bool) {
+	var nearest Memory
+	found := false
+	best := math.Inf(1)
+	for _, m := range p.Recall(kind) {
+		if d := p.view.world.distance(p.Self.X, p.Self.Y, m.X, m.Y); d < best {
+			nearest, found, best = m, true, d
+		}
+	}
+	return nearest, found
+}
+
+// avoidRemembered returns steering away from larger enemies remembered
+// within fleeRadius that are not in sight any more, and how urgent it is.
+// Threats in sight are left to Sense.
+func (e *Entity) avoidRemembered(memories []Memory, fleeRadius float64, now int) (float64, float64, float64) {
+	var steerX, steerY, urgency float64
+	for _, m := range memories {
+		if m.Tick == now || m.Size <= e.Width {
+			continue
+		}
+		mx, my := e.world.unwrap(e.X, e.Y, m.X, m.Y)
+		dx := e.X - mx
+		dy := e.Y - my
+		d := math.Sqrt(dx*dx + dy*dy)
+		if d == 0 || d >= fleeRadius {
+			continue
+		}
+		closeness := (1 - d/fleeRadius) * m.Confidence
+		steerX += dx / d * closeness
+		steerY += dy / d * closeness
+		urgency += 100 * closeness
+	}
+	length := math.Sqrt(steerX*steerX + steerY*steerY)
+	if length == 0 {
+		return 0, 0, 0
+	}
+	return steerX / length * 0.1, steerY / length * 0.1, urgency
+}
@@ -0,0 +1,63 @@
+package sim
+
+import "math"
+
+const (
+	defaultMaxEnergy        = 100.0
+	defaultBasalMetabolism  = 0.05
+	defaultMovementCost     = 0.02
+	defaultAttackCost       = 2.0
+	defaultFoodEnergy       = 5.0
+	defaultStarvationDamage = 5.0
+)
+
+func (c Config) maxEnergy() float64 {
+	return orDefault(c.MaxEnergy, defaultMaxEnergy)
+}
+
+func (c Config) attackCost() float64 {
+	return orDefaultIfNegative(c.AttackCost, defaultAttackCost)
+}
+
+func (c Config) foodEnergy() float64 {
+	return orDefault(c.FoodEnergy, defaultFoodEnergy)
+}
+
+// startEnergy is what entities are born with: half full, so they hunt
+// before they need to eat.
+func (c Config) startEnergy() float64 {
+	return c.maxEnergy() / 2
+}
+
+// Metabolise burns the energy the entity needs to stay alive and to move at
+// its current speed. Bigger and faster entities burn more. With no energy
+// left the entity starves, losing health instead.
+func (e *Entity) Metabolise(deltaTime float64) {
+	c := e.world.config
+	speed := math.Sqrt(e.VX*e.VX + e.VY*e.VY)
+	burn := (orDefaultIfNegative(c.BasalMetabolism, defaultBasalMetabolism)*e.Width +
+		orDefaultIfNegative(c.MovementCost, defaultMovementCost)*speed) * deltaTime
+	e.Spend(burn)
+
+	if e.Energy <= 0 {
+		e.Health -= orDefaultIfNegative(c.StarvationDamage, defaultStarvationDamage) * deltaTime
+	}
+}
+
+// Spend takes energy from the entity, stopping at empty.
+func (e *Entity) Spend(energy float64) {
+	e.Energy = math.Max(0, e.Energy-energy)
+	e.updateHunger()
+}
+
+// Feed restores energy to the entity, up to its maximum.
+func (e *Entity) Feed(energy float64) {
+	e.Energy = math.Min(e.world.config.maxEnergy(), e.Energy+energy)
+	e.updateHunger()
+}
+
+// updateHunger keeps HungerLevel, which brains decide on, in step with
+// energy.
+func (e *Entity) updateHunger() {
+	e.HungerLevel = 100 * (1 - e.Energy/e.world.config.maxEnergy())
+}
@@ -0,0 +1,203 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+)
+
+const NeuralState State = "Neural"
+
+// Network is a small fully connected feed-forward network with tanh
+// activations. Weights are stored layer by layer, each neuron's incoming
+// weights followed by its bias.
+type Network struct {
+	Layers  []int // Neurons per layer, inputs first and outputs last
+	Weights []float64
+}
+
+// NewNetwork creates a network with the given layer sizes and random
+// weights.
+func NewNetwork(layers []int, rng *rand.Rand) *Network {
+	n := &Network{Layers: append([]int(nil), layers...)}
+	n.Weights = make([]float64, n.weightCount())
+	for i := range n.Weights {
+		n.Weights[i] = rng.NormFloat64() * 0.5
+	}
+	return n
+}
+
+func (n *Network) weightCount() int {
+	count := 0
+	for l := 1; l < len(n.Layers); l++ {
+		count += (n.Layers[l-1] + 1) * n.Layers[l]
+	}
+	return count
+}
+
+// Validate checks the weights match the layer sizes.
+func (n *Network) Validate() error {
+	if len(n.Layers) < 2 {
+		return fmt.Errorf("network needs at least an input and output layer, has %d", len(n.Layers))
+	}
+	if n.Layers[0] != NeuralInputs || n.Layers[len(n.Layers)-1] != NeuralOutputs {
+		return fmt.Errorf("network must take %d inputs and give %d outputs, has %v", NeuralInputs, NeuralOutputs, n.Layers)
+	}
+	if want := n.weightCount(); len(n.Weights) != want {
+		return fmt.Errorf("network %v needs %d weights, has %d", n.Layers, want, len(n.Weights))
+	}
+	return nil
+}
+
+// Forward runs the inputs through the network and returns its outputs,
+// each in [-1, 1].
+func (n *Network) Forward(inputs []float64) []float64 {
+	activations := inputs
+	w := 0
+	for l := 1; l < len(n.Layers); l++ {
+		next := make([]float64, n.Layers[l])
+		for j := range next {
+			sum := 0.0
+			for _, a := range activations {
+				sum += a * n.Weights[w]
+				w++
+			}
+			sum += n.Weights[w] // Bias
+			w++
+			next[j] = math.Tanh(sum)
+		}
+		activations = next
+	}
+	return activations
+}
+
+// Clone returns a deep copy of the network.
+func (n *Network) Clone() *Network {
+	return &Network{
+		Layers:  append([]int(nil), n.Layers...),
+		Weights: append([]float64(nil), n.Weights...),
+	}
+}
+
+// SaveNetworks writes networks to a JSON file.
+func SaveNetworks(path string, networks []*Network) error {
+	data, err := json.MarshalIndent(networks, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadNetworks reads networks written by SaveNetworks.
+func LoadNetworks(path string) ([]*Network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var networks []*Network
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("parsing networks %s: %w", path, err)
+	}
+	for i, n := range networks {
+		if err := n.Validate(); err != nil {
+			return nil, fmt.Errorf("network %d in %s: %w", i, path, err)
+		}
+	}
+	return networks, nil
+}
+
+const (
+	// NeuralInputs is the number of sensor inputs a NeuralBrain feeds its
+	// network, and NeuralOutputs the steering outputs it reads back.
+	NeuralInputs  = 15
+	NeuralOutputs = 2
+
+	neuralSenseRadius = 300.0 // Distance sensor readings are scaled by
+)
+
+// NeuralBrain steers an entity with a network. It only steers; attacking
+// and eating still happen on contact.
+type NeuralBrain struct {
+	Net *Network
+}
+
+func NewNeuralBrain(n *Network) *NeuralBrain {
+	return &NeuralBrain{Net: n}
+}
+
+func (b *NeuralBrain) Decide(p *Perception) Decision {
+	out := b.Net.Forward(NeuralSensors(p))
+	return Decision{
+		State:    NeuralState,
+		TeamNeed: p.Self.EvaluateTeamNeed(p.InjuredWithin(teamNeedRange)),
+		SteerX:   out[0] * 0.1,
+		SteerY:   out[1] * 0.1,
+	}
+}
+
+// NeuralSensors reads the entity's surroundings into NeuralInputs values,
+// each roughly in [-1, 1]:
+//
+//	0-2   nearest food direction x, y and distance
+//	3-6   nearest enemy direction x, y, distance and size relative to self
+//	7-10  nearest ally direction x, y, distance and health
+//	11    own health
+//	12    own hunger
+//	13-14 position across the arena x, y, from -1 at one wall to 1 at the other
+//
+// Directions are unit vectors and distances are scaled so 1 means at or
+// beyond sensing range, which is also what is reported when there is
+// nothing to sense. Directions and distances take the shortest way round
+// a wrapping arena, which has no walls, so the position inputs are 0 there.
+func NeuralSensors(p *Perception) []float64 {
+	e := p.Self
+	in := make([]float64, 0, NeuralInputs)
+
+	towards := func(x, y float64) (float64, float64, float64) {
+		x, y = e.world.unwrap(e.X, e.Y, x, y)
+		dx, dy := x-e.X, y-e.Y
+		d := math.Sqrt(dx*dx + dy*dy)
+		if d == 0 {
+			return 0, 0, 0
+		}
+		return dx / d, dy / d, math.Min(1, d/neuralSenseRadius)
+	}
+
+	if food := p.NearestFood(); food != nil {
+		x, y, d := towards(food.X, food.Y)
+		in = append(in, x, y, d)
+	} else {
+		in = append(in, 0, 0, 1)
+	}
+
+	enemy := p.NearestEntity(func(other *Entity) bool {
+		return other.Active && other.TeamID != e.TeamID
+	})
+	if enemy != nil {
+		x, y, d := towards(enemy.X, enemy.Y)
+		in = append(in, x, y, d, math.Min(2, enemy.Width/e.Width)-1)
+	} else {
+		in = append(in, 0, 0, 1, 0)
+	}
+
+	ally := p.NearestEntity(func(other *Entity) bool {
+		return other.Active && other.TeamID == e.TeamID && other != e
+	})
+	if ally != nil {
+		x, y, d := towards(ally.X, ally.Y)
+		in = append(in, x, y, d, ally.Health/100)
+	} else {
+		in = append(in, 0, 0, 1, 1)
+	}
+
+	in = append(in, e.Health/100, e.HungerLevel/100)
+	if e.world.config.Wrap {
+		in = append(in, 0, 0)
+	} else {
+		width, height := p.Bounds()
+		in = append(in, 2*e.X/width-1, 2*e.Y/height-1)
+	}
+	return in
+}
@@ -0,0 +1,60 @@
+package sim
+
+import (
+	"math/rand"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoadNetworks(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	saved := []*Network{
+		NewNetwork([]int{NeuralInputs, 8, NeuralOutputs}, rng),
+		NewNetwork([]int{NeuralInputs, NeuralOutputs}, rng),
+	}
+	path := filepath.Join(t.TempDir(), "networks.json")
+	if err := SaveNetworks(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadNetworks(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(saved, loaded) {
+		t.Error("loaded networks differ from the ones saved")
+	}
+}
+
+func TestLoadNetworksRejectsBadNetworks(t *testing.T) {
+	for name, content := range map[string]string{
+		"not json":        `[{`,
+		"one layer":       `[{"Layers": [15], "Weights": []}]`,
+		"wrong inputs":    `[{"Layers": [3, 2], "Weights": [0, 0, 0, 0, 0, 0, 0, 0]}]`,
+		"missing weights": `[{"Layers": [15, 2], "Weights": [0, 0, 0]}]`,
+	} {
+		if _, err := LoadNetworks(writeTestFile(t, "networks.json", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadNetworks(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
+
+func TestForwardOutputsInRange(t *testing.T) {
+	n := NewNetwork([]int{NeuralInputs, 8, NeuralOutputs}, rand.New(rand.NewSource(1)))
+	inputs := make([]float64, NeuralInputs)
+	for i := range inputs {
+		inputs[i] = float64(i) - 7
+	}
+	outputs := n.Forward(inputs)
+	if len(outputs) != NeuralOutputs {
+		t.Fatalf("got %d outputs, want %d", len(outputs), NeuralOutputs)
+	}
+	for _, o := range outputs {
+		if o < -1 || o > 1 {
+			t.Errorf("output %v outside [-1, 1]", o)
+		}
+	}
+}
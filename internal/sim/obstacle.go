@@ -0,0 +1,334 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+// Point is a position in the arena.
+type Point struct {
+	X, Y float64
+}
+
+// Obstacle is a static shape entities cannot pass through or see through.
+// Shape is one of:
+//
+//	circle   centred on X, Y with Radius
+//	box      with its top left corner at X, Y and Width by Height
+//	polygon  through Points, in order
+type Obstacle struct {
+	Shape         string
+	X, Y          float64
+	Radius        float64 `json:",omitempty"`
+	Width, Height float64 `json:",omitempty"`
+	Points        []Point `json:",omitempty"`
+
+	// Filled in by prepare
+	outline                []Point // Boxes and polygons as a closed outline
+	minX, minY, maxX, maxY float64 // Bounding box
+	centreX, centreY       float64
+}
+
+// Map is a set of obstacles, as loaded by LoadMap.
+type Map struct {
+	Obstacles []Obstacle
+}
+
+// LoadMap reads a map from a JSON file such as
+//
+//	{"Obstacles": [
+//		{"Shape": "circle", "X": 500, "Y": 300, "Radius": 60},
+//		{"Shape": "box", "X": 200, "Y": 100, "Width": 20, "Height": 300},
+//		{"Shape": "polygon", "Points": [{"X": 700, "Y": 400}, {"X": 800, "Y": 450}, {"X": 720, "Y": 520}]}
+//	]}
+func LoadMap(path string) (*Map, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var m Map
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parsing map %s: %w", path, err)
+	}
+	for i := range m.Obstacles {
+		if err := m.Obstacles[i].prepare(); err != nil {
+			return nil, fmt.Errorf("map %s: obstacle %d: %w", path, i, err)
+		}
+	}
+	return &m, nil
+}
+
+// prepare checks the obstacle and works out its outline and bounds.
+func (o *Obstacle) prepare() error {
+	switch o.Shape {
+	case "circle":
+		if o.Radius <= 0 {
+			return fmt.Errorf("circle needs a positive radius")
+		}
+		o.outline = nil
+		o.minX, o.minY, o.maxX, o.maxY = o.X-o.Radius, o.Y-o.Radius, o.X+o.Radius, o.Y+o.Radius
+		o.centreX, o.centreY = o.X, o.Y
+		return nil
+	case "box":
+		if o.Width <= 0 || o.Height <= 0 {
+			return fmt.Errorf("box needs a positive width and height")
+		}
+		o.outline = []Point{{o.X, o.Y}, {o.X + o.Width, o.Y}, {o.X + o.Width, o.Y + o.Height}, {o.X, o.Y + o.Height}}
+	case "polygon":
+		if len(o.Points) < 3 {
+			return fmt.Errorf("polygon needs at least 3 points")
+		}
+		area := 0.0
+		for i, a := range o.Points {
+			b := o.Points[(i+1)%len(o.Points)]
+			if a == b {
+				return fmt.Errorf("polygon repeats point %d", i)
+			}
+			area += a.X*b.Y - b.X*a.Y
+		}
+		if area == 0 {
+			return fmt.Errorf("polygon has no area")
+		}
+		o.outline = o.Points
+	default:
+		return fmt.Errorf("unknown shape %q", o.Shape)
+	}
+
+	o.minX, o.minY = math.Inf(1), math.Inf(1)
+	o.maxX, o.maxY = math.Inf(-1), math.Inf(-1)
+	o.centreX, o.centreY = 0, 0
+	for _, p := range o.outline {
+		o.minX, o.minY = math.Min(o.minX, p.X), math.Min(o.minY, p.Y)
+		o.maxX, o.maxY = math.Max(o.maxX, p.X), math.Max(o.maxY, p.Y)
+		o.centreX += p.X
+		o.centreY += p.Y
+	}
+	o.centreX /= float64(len(o.outline))
+	o.centreY /= float64(len(o.outline))
+	return nil
+}
+
+// near reports whether a point is within margin of the bounding box.
+func (o *Obstacle) near(x, y, margin float64) bool {
+	return x >= o.minX-margin && x <= o.maxX+margin && y >= o.minY-margin && y <= o.maxY+margin
+}
+
+// contains reports whether a point is inside the obstacle.
+func (o *Obstacle) contains(x, y float64) bool {
+	if !o.near(x, y, 0) {
+		return false
+	}
+	if o.outline == nil {
+		return distance(x, y, o.X, o.Y) < o.Radius
+	}
+	// Count crossings of a ray heading right from the point
+	inside := false
+	for i, a := range o.outline {
+		b := o.outline[(i+1)%len(o.outline)]
+		if (a.Y > y) != (b.Y > y) && x < a.X+(y-a.Y)*(b.X-a.X)/(b.Y-a.Y) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// closestPoint returns the point on the obstacle's boundary closest to
+// (x, y).
+func (o *Obstacle) closestPoint(x, y float64) (float64, float64) {
+	if o.outline == nil {
+		d := distance(x, y, o.X, o.Y)
+		if d == 0 {
+			return o.X + o.Radius, o.Y
+		}
+		return o.X + (x-o.X)/d*o.Radius, o.Y + (y-o.Y)/d*o.Radius
+	}
+	bestX, bestY, best := 0.0, 0.0, math.Inf(1)
+	for i, a := range o.outline {
+		b := o.outline[(i+1)%len(o.outline)]
+		px, py := closestOnSegment(x, y, a.X, a.Y, b.X, b.Y)
+		if d := distance(x, y, px, py); d < best {
+			bestX, bestY, best = px, py, d
+		}
+	}
+	return bestX, bestY
+}
+
+func closestOnSegment(x, y, ax, ay, bx, by float64) (float64, float64) {
+	dx, dy := bx-ax, by-ay
+	lengthSquared := dx*dx + dy*dy
+	if lengthSquared == 0 {
+		return ax, ay
+	}
+	t := clamp(((x-ax)*dx+(y-ay)*dy)/lengthSquared, 0, 1)
+	return ax + t*dx, ay + t*dy
+}
+
+// blocks reports whether the segment between two points passes through
+// the obstacle.
+func (o *Obstacle) blocks(x1, y1, x2, y2 float64) bool {
+	if math.Max(x1, x2) < o.minX || math.Min(x1, x2) > o.maxX || math.Max(y1, y2) < o.minY || math.Min(y1, y2) > o.maxY {
+		return false
+	}
+	if o.outline == nil {
+		px, py := closestOnSegment(o.X, o.Y, x1, y1, x2, y2)
+		return distance(px, py, o.X, o.Y) < o.Radius
+	}
+	if o.contains(x1, y1) || o.contains(x2, y2) {
+		return true
+	}
+	for i, a := range o.outline {
+		b := o.outline[(i+1)%len(o.outline)]
+		if segmentsCross(x1, y1, x2, y2, a.X, a.Y, b.X, b.Y) {
+			return true
+		}
+	}
+	return false
+}
+
+func segmentsCross(ax, ay, bx, by, cx, cy, dx, dy float64) bool {
+	cross := func(ox, oy, px, py, qx, qy float64) float64 {
+		return (px-ox)*(qy-oy) - (py-oy)*(qx-ox)
+	}
+	d1 := cross(cx, cy, dx, dy, ax, ay)
+	d2 := cross(cx, cy, dx, dy, bx, by)
+	d3 := cross(ax, ay, bx, by, cx, cy)
+	d4 := cross(ax, ay, bx, by, dx, dy)
+	return (d1 > 0) != (d2 > 0) && (d3 > 0) != (d4 > 0)
+}
+
+// SetObstacles places obstacles in the arena. They stay across restarts.
+func (w *World) SetObstacles(obstacles []Obstacle) error {
+	prepared := make([]Obstacle, len(obstacles))
+	copy(prepared, obstacles)
+	for i := range prepared {
+		if err := prepared[i].prepare(); err != nil {
+			return fmt.Errorf("obstacle %d: %w", i, err)
+		}
+	}
+	w.obstacles = prepared
+	w.navDirty = true
+	return nil
+}
+
+// Obstacles returns the obstacles in the arena.
+func (w *World) Obstacles() []Obstacle {
+	return w.obstacles
+}
+
+// lineOfSight reports whether nothing blocks the segment between two
+// points.
+func (w *World) lineOfSight(x1, y1, x2, y2 float64) bool {
+	for i := range w.obstacles {
+		if w.obstacles[i].blocks(x1, y1, x2, y2) {
+			return false
+		}
+	}
+	return true
+}
+
+// insideObstacle reports whether a circle of radius r at (x, y) overlaps
+// any obstacle.
+func (w *World) insideObstacle(x, y, r float64) bool {
+	for i := range w.obstacles {
+		o := &w.obstacles[i]
+		if !o.near(x, y, r) {
+			continue
+		}
+		if o.contains(x, y) {
+			return true
+		}
+		px, py := o.closestPoint(x, y)
+		if distance(x, y, px, py) < r {
+			return true
+		}
+	}
+	return false
+}
+
+// openPoint draws a random point in the arena clear of obstacles by at
+// least margin. It gives up after a number of tries and returns the last
+// point drawn, which collision resolution will push out.
+func (w *World) openPoint(margin float64) (float64, float64) {
+	x := w.randFloat(0, w.canvasWidth)
+	y := w.randFloat(0, w.canvasHeight)
+	for tries := 0; tries < 100 && w.insideObstacle(x, y, margin); tries++ {
+		x = w.randFloat(0, w.canvasWidth)
+		y = w.randFloat(0, w.canvasHeight)
+	}
+	return x, y
+}
+
+// resolveObstacles pushes the entity out of any obstacle it overlaps and
+// takes away the part of its velocity heading into it, so it slides along
+// the surface.
+func (e *Entity) resolveObstacles() {
+	obstacles := e.world.obstacles
+	for i := range obstacles {
+		o := &obstacles[i]
+		if !o.near(e.X, e.Y, e.Width) {
+			continue
+		}
+		px, py := o.closestPoint(e.X, e.Y)
+		nx, ny := e.X-px, e.Y-py
+		d := math.Sqrt(nx*nx + ny*ny)
+		inside := o.contains(e.X, e.Y)
+		if !inside && d >= e.Width {
+			continue
+		}
+		if d == 0 {
+			// On the boundary, so push away from the centre
+			nx, ny = e.X-o.centreX, e.Y-o.centreY
+			d = math.Sqrt(nx*nx + ny*ny)
+			if d == 0 {
+				nx, ny, d = 1, 0, 1
+			}
+		} else if inside {
+			// The normal points inwards from inside the shape
+			nx, ny = -nx, -ny
+		}
+		nx, ny = nx/d, ny/d
+		e.X, e.Y = px+nx*e.Width, py+ny*e.Width
+		if into := e.VX*nx + e.VY*ny; into < 0 {
+			e.VX -= into * nx
+			e.VY -= into * ny
+		}
+	}
+}
+
+// detour returns a point to head for instead of (x, y) when an obstacle
+// is in the way: just past the side of the first blocking obstacle that
+// turns least away from the target.
+func (e *Entity) detour(x, y float64) (float64, float64) {
+	obstacles := e.world.obstacles
+	var blocking *Obstacle
+	best := math.Inf(1)
+	for i := range obstacles {
+		o := &obstacles[i]
+		if o.blocks(e.X, e.Y, x, y) {
+			if d := distance(e.X, e.Y, o.centreX, o.centreY); d < best {
+				blocking, best = o, d
+			}
+		}
+	}
+	if blocking == nil {
+		return x, y
+	}
+
+	// Go around the obstacle's bounding circle, on the side nearer the
+	// target
+	radius := math.Max(blocking.maxX-blocking.minX, blocking.maxY-blocking.minY)/2 + 2*e.Width
+	ox, oy := e.X-blocking.centreX, e.Y-blocking.centreY
+	d := math.Sqrt(ox*ox + oy*oy)
+	if d == 0 {
+		return x, y
+	}
+	ox, oy = ox/d, oy/d
+	left := Point{blocking.centreX - oy*radius, blocking.centreY + ox*radius}
+	right := Point{blocking.centreX + oy*radius, blocking.centreY - ox*radius}
+	if distance(left.X, left.Y, x, y) < distance(right.X, right.Y, x, y) {
+		return left.X, left.Y
+	}
+	return right.X, right.Y
+}
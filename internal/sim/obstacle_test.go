@@ -0,0 +1,204 @@
+package sim
+
+import (
+	"math"
+	"path/filepath"
+	"testing"
+)
+
+// testObstacles returns one prepared obstacle of each shape: a circle
+// centred on (300, 300), a box over (100, 100)-(150, 150) and a right
+// triangle with its square corner at (500, 100).
+func testObstacles(t *testing.T) map[string]*Obstacle {
+	t.Helper()
+	obstacles := map[string]*Obstacle{
+		"circle":  {Shape: "circle", X: 300, Y: 300, Radius: 40},
+		"box":     {Shape: "box", X: 100, Y: 100, Width: 50, Height: 50},
+		"polygon": {Shape: "polygon", Points: []Point{{500, 100}, {600, 100}, {500, 200}}},
+	}
+	for name, o := range obstacles {
+		if err := o.prepare(); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+	}
+	return obstacles
+}
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		name  string
+		o     Obstacle
+		valid bool
+	}{
+		{"circle", Obstacle{Shape: "circle", Radius: 1}, true},
+		{"circle without radius", Obstacle{Shape: "circle"}, false},
+		{"box", Obstacle{Shape: "box", Width: 1, Height: 1}, true},
+		{"flat box", Obstacle{Shape: "box", Width: 1}, false},
+		{"triangle", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {1, 0}, {0, 1}}}, true},
+		{"clockwise triangle", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {0, 1}, {1, 0}}}, true},
+		{"two points", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {1, 0}}}, false},
+		{"repeated point", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {1, 0}, {1, 0}, {0, 1}}}, false},
+		{"closed outline", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {1, 0}, {0, 1}, {0, 0}}}, false},
+		{"collinear points", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {1, 1}, {2, 2}}}, false},
+		{"folded back", Obstacle{Shape: "polygon", Points: []Point{{0, 0}, {2, 0}, {1, 0}, {3, 0}}}, false},
+		{"unknown shape", Obstacle{Shape: "star"}, false},
+	}
+	for _, test := range tests {
+		err := test.o.prepare()
+		if test.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected an error", test.name)
+		}
+	}
+}
+
+func TestContains(t *testing.T) {
+	obstacles := testObstacles(t)
+	tests := []struct {
+		shape string
+		x, y  float64
+		want  bool
+	}{
+		{"circle", 300, 300, true},
+		{"circle", 330, 320, true},
+		{"circle", 335, 335, false}, // Inside the bounding box only
+		{"circle", 400, 300, false},
+		{"box", 125, 125, true},
+		{"box", 149, 101, true},
+		{"box", 160, 125, false},
+		{"box", 125, 90, false},
+		{"polygon", 520, 120, true},
+		{"polygon", 590, 190, false}, // Inside the bounding box only
+		{"polygon", 450, 150, false},
+	}
+	for _, test := range tests {
+		if got := obstacles[test.shape].contains(test.x, test.y); got != test.want {
+			t.Errorf("%s contains (%v, %v) = %v, want %v", test.shape, test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	obstacles := testObstacles(t)
+	tests := []struct {
+		shape          string
+		x1, y1, x2, y2 float64
+		want           bool
+	}{
+		{"circle", 200, 300, 400, 300, true},
+		{"circle", 200, 250, 400, 250, false},
+		{"circle", 200, 200, 300, 300, true}, // Ends inside
+		{"circle", 200, 200, 270, 270, false},
+		{"box", 50, 125, 200, 125, true},
+		{"box", 50, 50, 200, 200, true},
+		{"box", 125, 125, 130, 130, true}, // Wholly inside
+		{"box", 50, 90, 200, 90, false},
+		{"polygon", 450, 150, 650, 150, true},
+		{"polygon", 560, 190, 590, 160, false}, // Inside the bounding box only
+		{"polygon", 450, 250, 650, 250, false},
+	}
+	for _, test := range tests {
+		if got := obstacles[test.shape].blocks(test.x1, test.y1, test.x2, test.y2); got != test.want {
+			t.Errorf("%s blocks (%v, %v)-(%v, %v) = %v, want %v",
+				test.shape, test.x1, test.y1, test.x2, test.y2, got, test.want)
+		}
+	}
+}
+
+func TestSegmentsCross(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Point
+		c, d Point
+		want bool
+	}{
+		{"crossing", Point{0, 0}, Point{10, 10}, Point{0, 10}, Point{10, 0}, true},
+		{"apart", Point{0, 0}, Point{10, 0}, Point{0, 5}, Point{10, 5}, false},
+		{"short of each other", Point{0, 0}, Point{4, 4}, Point{0, 10}, Point{10, 0}, false},
+		{"collinear", Point{0, 0}, Point{10, 0}, Point{5, 0}, Point{15, 0}, false},
+	}
+	for _, test := range tests {
+		got := segmentsCross(test.a.X, test.a.Y, test.b.X, test.b.Y, test.c.X, test.c.Y, test.d.X, test.d.Y)
+		if got != test.want {
+			t.Errorf("%s: segmentsCross = %v, want %v", test.name, got, test.want)
+		}
+		got = segmentsCross(test.c.X, test.c.Y, test.d.X, test.d.Y, test.a.X, test.a.Y, test.b.X, test.b.Y)
+		if got != test.want {
+			t.Errorf("%s swapped: segmentsCross = %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestResolveObstacles(t *testing.T) {
+	obstacles := testObstacles(t)
+	diagonal := 5 / math.Sqrt2
+	tests := []struct {
+		name         string
+		shape        string
+		x, y, vx, vy float64
+		wantX, wantY float64
+		wantVX       float64
+	}{
+		{"circle clear", "circle", 200, 300, 1, 0, 200, 300, 1},
+		{"circle overlapping", "circle", 257, 300, 1, 0, 255, 300, 0},
+		{"circle inside", "circle", 280, 300, 1, 0, 255, 300, 0},
+		{"circle on boundary", "circle", 260, 300, 1, 0, 255, 300, 0},
+		{"circle at centre", "circle", 300, 300, -1, 0, 345, 300, 0},
+		{"box clear", "box", 80, 125, 1, 0, 80, 125, 1},
+		{"box overlapping", "box", 97, 125, 1, 0, 95, 125, 0},
+		{"box inside", "box", 110, 125, 1, 0, 95, 125, 0},
+		{"box on left edge", "box", 100, 125, 1, 0, 95, 125, 0},
+		{"box on right edge", "box", 150, 125, -1, 0, 155, 125, 0},
+		{"box moving away", "box", 97, 125, -1, 0, 95, 125, -1},
+		{"polygon overlapping", "polygon", 497, 150, 1, 0, 495, 150, 0},
+		{"polygon inside", "polygon", 510, 150, 1, 0, 495, 150, 0},
+		{"polygon on hypotenuse", "polygon", 550, 150, 0, 0, 550 + diagonal, 150 + diagonal, 0},
+	}
+	for _, test := range tests {
+		w := NewWorld(DefaultConfig(), 1000, 600)
+		w.obstacles = []Obstacle{*obstacles[test.shape]}
+		e := &Entity{world: w, X: test.x, Y: test.y, VX: test.vx, VY: test.vy, Width: 5}
+		e.resolveObstacles()
+		if math.Abs(e.X-test.wantX) > 1e-9 || math.Abs(e.Y-test.wantY) > 1e-9 || math.Abs(e.VX-test.wantVX) > 1e-9 {
+			t.Errorf("%s: ended at (%v, %v) moving %v, want (%v, %v) moving %v",
+				test.name, e.X, e.Y, e.VX, test.wantX, test.wantY, test.wantVX)
+		}
+		if w.obstacles[0].contains(e.X, e.Y) {
+			t.Errorf("%s: still inside at (%v, %v)", test.name, e.X, e.Y)
+		}
+	}
+}
+
+func TestLoadMap(t *testing.T) {
+	m, err := LoadMap(writeTestFile(t, "map.json", `{"Obstacles": [
+		{"Shape": "circle", "X": 500, "Y": 300, "Radius": 60},
+		{"Shape": "box", "X": 0, "Y": 0, "Width": 20, "Height": 300},
+		{"Shape": "polygon", "Points": [{"X": 700, "Y": 400}, {"X": 800, "Y": 450}, {"X": 720, "Y": 520}]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Obstacles) != 3 {
+		t.Fatalf("loaded %d obstacles, want 3", len(m.Obstacles))
+	}
+	// Loaded obstacles are prepared
+	if !m.Obstacles[1].contains(10, 150) || !m.Obstacles[2].contains(740, 450) {
+		t.Error("loaded obstacles do not contain points inside them")
+	}
+
+	for name, content := range map[string]string{
+		"not json":          `{"Obstacles": [`,
+		"unknown shape":     `{"Obstacles": [{"Shape": "star"}]}`,
+		"degenerate circle": `{"Obstacles": [{"Shape": "circle", "X": 1, "Y": 1}]}`,
+		"flat polygon":      `{"Obstacles": [{"Shape": "polygon", "Points": [{"X": 0, "Y": 0}, {"X": 1, "Y": 1}, {"X": 2, "Y": 2}]}]}`,
+	} {
+		if _, err := LoadMap(writeTestFile(t, "map.json", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if _, err := LoadMap(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("missing file: expected an error")
+	}
+}
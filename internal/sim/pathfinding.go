@@ -0,0 +1,323 @@
+package sim
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+)
+
+// navCellSize is the edge length of a pathfinding grid cell.
+const navCellSize = 20.0
+
+// navClearance is how far a cell's centre must be from any obstacle for
+// the cell to be open.
+const navClearance = navCellSize * 0.75
+
+const (
+	defaultPathBudget = 50 // Path searches per tick across all entities
+	maxFlowFields     = 64 // Flow fields kept before the cache is cleared
+)
+
+// navGrid is a coarse occupancy grid of the arena that paths are planned
+// over. It is rebuilt whenever the obstacles or the arena change, and only
+// exists when there are obstacles to plan around.
+type navGrid struct {
+	cols, rows int
+	blocked    []bool
+
+	mu    sync.Mutex
+	flows map[int]*flowField // Flow fields by goal cell
+}
+
+// flowField points every open cell one step closer to a goal. Fields are
+// shared by every entity heading to the same goal, so hundreds of entities
+// can path to one place for the price of a single search.
+type flowField struct {
+	once sync.Once
+	next []int // Neighbouring cell to step to, -1 at the goal or when unreachable
+}
+
+// prepareNav rebuilds the grid if the obstacles or arena have changed and
+// clears out old flow fields. It runs before entities decide.
+func (w *World) prepareNav() {
+	if w.navDirty {
+		w.navDirty = false
+		w.nav = nil
+		if len(w.obstacles) > 0 {
+			w.nav = newNavGrid(w)
+		}
+	}
+	if w.nav != nil && len(w.nav.flows) > maxFlowFields {
+		w.nav.flows = make(map[int]*flowField)
+	}
+
+	// Spread the path searches over the tick budget: an entity may only
+	// plan on the ticks its ID comes round to
+	active := 0
+	for _, e := range w.entities {
+		if e.Active {
+			active++
+		}
+	}
+	budget := w.config.PathBudget
+	if budget <= 0 {
+		budget = defaultPathBudget
+	}
+	w.replanInterval = max(1, (active+budget-1)/budget)
+}
+
+func newNavGrid(w *World) *navGrid {
+	g := &navGrid{
+		cols:  int(math.Max(1, math.Ceil(w.canvasWidth/navCellSize))),
+		rows:  int(math.Max(1, math.Ceil(w.canvasHeight/navCellSize))),
+		flows: make(map[int]*flowField),
+	}
+	g.blocked = make([]bool, g.cols*g.rows)
+	for i := range g.blocked {
+		x, y := g.centre(i)
+		g.blocked[i] = w.insideObstacle(x, y, navClearance)
+	}
+	return g
+}
+
+func (g *navGrid) cellOf(x, y float64) int {
+	cx := int(clamp(math.Floor(x/navCellSize), 0, float64(g.cols-1)))
+	cy := int(clamp(math.Floor(y/navCellSize), 0, float64(g.rows-1)))
+	return cy*g.cols + cx
+}
+
+func (g *navGrid) centre(cell int) (float64, float64) {
+	return (float64(cell%g.cols) + 0.5) * navCellSize, (float64(cell/g.cols) + 0.5) * navCellSize
+}
+
+// neighbours calls visit with each open cell a step away from cell and the
+// cost of the step. Diagonal steps may not cut the corner of a blocked cell.
+func (g *navGrid) neighbours(cell int, visit func(next int, cost float64)) {
+	cx, cy := cell%g.cols, cell/g.cols
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			nx, ny := cx+dx, cy+dy
+			if (dx == 0 && dy == 0) || nx < 0 || ny < 0 || nx >= g.cols || ny >= g.rows {
+				continue
+			}
+			next := ny*g.cols + nx
+			if g.blocked[next] {
+				continue
+			}
+			cost := 1.0
+			if dx != 0 && dy != 0 {
+				if g.blocked[cy*g.cols+nx] || g.blocked[ny*g.cols+cx] {
+					continue
+				}
+				cost = math.Sqrt2
+			}
+			visit(next, cost)
+		}
+	}
+}
+
+// openQueue is a priority queue of cells for A* and Dijkstra.
+type openQueue struct {
+	cells    []int
+	priority []float64 // Indexed by cell
+}
+
+func (q *openQueue) Len() int {
+	return len(q.cells)
+}
+
+func (q *openQueue) Less(i, j int) bool {
+	return q.priority[q.cells[i]] < q.priority[q.cells[j]]
+}
+
+func (q *openQueue) Swap(i, j int) {
+	q.cells[i], q.cells[j] = q.cells[j], q.cells[i]
+}
+
+func (q *openQueue) Push(x any) {
+	q.cells = append(q.cells, x.(int))
+}
+
+func (q *openQueue) Pop() any {
+	last := q.cells[len(q.cells)-1]
+	q.cells = q.cells[:len(q.cells)-1]
+	return last
+}
+
+// pathSearch holds the scratch space for A*. Searches borrow one from the
+// world's pool so they can run on every decision worker at once.
+type pathSearch struct {
+	cost, priority []float64
+	from           []int
+	queue          openQueue
+}
+
+// findPath plans a path of cell centres from one point to another with
+// A*, leaving out the start. It returns nil when the goal cannot be
+// reached.
+func (g *navGrid) findPath(s *pathSearch, fromX, fromY, toX, toY float64) []Point {
+	start, goal := g.cellOf(fromX, fromY), g.cellOf(toX, toY)
+	if g.blocked[goal] {
+		return nil
+	}
+	n := len(g.blocked)
+	if len(s.cost) != n {
+		s.cost = make([]float64, n)
+		s.priority = make([]float64, n)
+		s.from = make([]int, n)
+	}
+	for i := range s.cost {
+		s.cost[i] = math.Inf(1)
+		s.from[i] = -1
+	}
+
+	// Octile distance never overestimates on an 8-connected grid
+	gx, gy := goal%g.cols, goal/g.cols
+	estimate := func(cell int) float64 {
+		dx := math.Abs(float64(cell%g.cols - gx))
+		dy := math.Abs(float64(cell/g.cols - gy))
+		return math.Max(dx, dy) + (math.Sqrt2-1)*math.Min(dx, dy)
+	}
+
+	s.queue = openQueue{cells: s.queue.cells[:0], priority: s.priority}
+	s.cost[start] = 0
+	s.priority[start] = estimate(start)
+	heap.Push(&s.queue, start)
+	for s.queue.Len() > 0 {
+		cell := heap.Pop(&s.queue).(int)
+		if cell == goal {
+			break
+		}
+		g.neighbours(cell, func(next int, step float64) {
+			if cost := s.cost[cell] + step; cost < s.cost[next] {
+				s.cost[next] = cost
+				s.from[next] = cell
+				s.priority[next] = cost + estimate(next)
+				heap.Push(&s.queue, next)
+			}
+		})
+	}
+	if goal != start && s.from[goal] == -1 {
+		return nil
+	}
+
+	var path []Point
+	for cell := goal; cell != start; cell = s.from[cell] {
+		x, y := g.centre(cell)
+		path = append(path, Point{x, y})
+	}
+	// Built backwards, and the last step is the goal itself
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	if len(path) > 0 {
+		path[len(path)-1] = Point{toX, toY}
+	}
+	return path
+}
+
+// flowTo returns the flow field towards a goal cell, building it on first
+// use. Any decision worker may ask for it; the field built is the same
+// whichever does.
+func (g *navGrid) flowTo(goal int) *flowField {
+	g.mu.Lock()
+	f, ok := g.flows[goal]
+	if !ok {
+		f = &flowField{}
+		g.flows[goal] = f
+	}
+	g.mu.Unlock()
+
+	f.once.Do(func() {
+		n := len(g.blocked)
+		cost := make([]float64, n)
+		for i := range cost {
+			cost[i] = math.Inf(1)
+		}
+		f.next = make([]int, n)
+		for i := range f.next {
+			f.next[i] = -1
+		}
+		if g.blocked[goal] {
+			return
+		}
+
+		// Dijkstra outwards from the goal
+		q := &openQueue{priority: cost}
+		cost[goal] = 0
+		heap.Push(q, goal)
+		for q.Len() > 0 {
+			cell := heap.Pop(q).(int)
+			g.neighbours(cell, func(next int, step float64) {
+				if c := cost[cell] + step; c < cost[next] {
+					cost[next] = c
+					f.next[next] = cell
+					heap.Push(q, next)
+				}
+			})
+		}
+	})
+	return f
+}
+
+// FlowTowards returns steering towards a point along the flow field to it.
+// Flow fields are shared, which makes them the cheap way for many entities
+// to head for the same place, such as a spot a teammate called out.
+func (p *Perception) FlowTowards(x, y float64) (float64, float64) {
+	e := p.Self
+	g := p.view.world.nav
+	// Flow fields do not cross the edges of a wrapping arena, so entities
+	// there steer for the point directly and plan around what is in the way
+	if g == nil || p.view.world.config.Wrap || p.view.world.lineOfSight(e.X, e.Y, x, y) {
+		return e.steerTowards(x, y)
+	}
+	f := g.flowTo(g.cellOf(x, y))
+	next := f.next[g.cellOf(e.X, e.Y)]
+	if next < 0 {
+		return e.steerTowards(x, y)
+	}
+	nx, ny := g.centre(next)
+	return normalised(nx-e.X, ny-e.Y)
+}
+
+// pathPlan is an entity's cached path to a goal.
+type pathPlan struct {
+	goal      int     // Goal cell the path leads to
+	waypoints []Point // Still to visit, in order
+}
+
+// waypoint returns the next point on the entity's way to (x, y). The
+// entity keeps its path while the goal stays in the same cell and only
+// plans a new one on the ticks it is allowed to, falling back to heading
+// around the nearest obstacle until then.
+func (e *Entity) waypoint(x, y float64) (float64, float64) {
+	w := e.world
+	g := w.nav
+	goal := g.cellOf(x, y)
+	plan := &e.path
+
+	if plan.goal != goal || len(plan.waypoints) == 0 {
+		if (e.ID+w.ticks)%w.replanInterval != 0 {
+			return e.detour(x, y)
+		}
+		s, _ := w.searches.Get().(*pathSearch)
+		if s == nil {
+			s = &pathSearch{}
+		}
+		plan.goal = goal
+		plan.waypoints = g.findPath(s, e.X, e.Y, x, y)
+		w.searches.Put(s)
+		if plan.waypoints == nil {
+			return e.detour(x, y)
+		}
+	}
+
+	// Skip ahead to the furthest waypoint in sight
+	for len(plan.waypoints) > 1 && w.lineOfSight(e.X, e.Y, plan.waypoints[1].X, plan.waypoints[1].Y) {
+		plan.waypoints = plan.waypoints[1:]
+	}
+	if next := plan.waypoints[0]; distance(e.X, e.Y, next.X, next.Y) < navCellSize/2 && len(plan.waypoints) > 1 {
+		plan.waypoints = plan.waypoints[1:]
+	}
+	return plan.waypoints[0].X, plan.waypoints[0].Y
+}
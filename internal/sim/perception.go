@@ -0,0 +1,82 @@
+package sim
+
+import "math"
+
+// Entities perceive the world through a view cone: they see what is within
+// the configured view distance and inside the view angle centred on their
+// heading, unless an obstacle is in the way. The defaults see everything,
+// all round.
+
+// viewDistance returns how far entities see, infinite when unset.
+func (c Config) viewDistance() float64 {
+	if c.ViewDistance <= 0 {
+		return math.Inf(1)
+	}
+	return c.ViewDistance
+}
+
+// viewCos returns the cosine of half the view angle, or -1 for all round
+// vision.
+func (c Config) viewCos() float64 {
+	if c.ViewAngle <= 0 || c.ViewAngle >= 360 {
+		return -1
+	}
+	return math.Cos(c.ViewAngle / 2 * math.Pi / 180)
+}
+
+// sees reports whether Self perceives a point.
+func (p *Perception) sees(x, y float64) bool {
+	c := &p.view.world.config
+	e := p.Self
+	x, y = p.view.world.unwrap(e.X, e.Y, x, y)
+	dx := x - e.X
+	dy := y - e.Y
+	distanceSquared := dx*dx + dy*dy
+	if limit := c.viewDistance(); distanceSquared > limit*limit {
+		return false
+	}
+	if cos := c.viewCos(); cos != -1 && distanceSquared != 0 {
+		// Inside the cone when the angle to the point is within half the
+		// view angle of the heading
+		dot := dx*math.Cos(e.Heading) + dy*math.Sin(e.Heading)
+		if dot < cos*math.Sqrt(distanceSquared) {
+			return false
+		}
+	}
+	return p.view.world.lineOfSight(e.X, e.Y, x, y)
+}
+
+// perceivedEntities filters entities down to the ones Self sees, in place.
+func (p *Perception) perceivedEntities(entities []*Entity) []*Entity {
+	if p.omniscient() {
+		return entities
+	}
+	seen := entities[:0]
+	for _, other := range entities {
+		if p.sees(other.X, other.Y) {
+			seen = append(seen, other)
+		}
+	}
+	return seen
+}
+
+// perceivedFood filters food down to the items Self sees, in place.
+func (p *Perception) perceivedFood(foods []*Food) []*Food {
+	if p.omniscient() {
+		return foods
+	}
+	seen := foods[:0]
+	for _, f := range foods {
+		if p.sees(f.X, f.Y) {
+			seen = append(seen, f)
+		}
+	}
+	return seen
+}
+
+// omniscient reports whether entities see all round without limit or
+// obstacles, in which case there is nothing to filter.
+func (p *Perception) omniscient() bool {
+	w := p.view.world
+	return w.config.ViewDistance <= 0 && w.config.viewCos() == -1 && len(w.obstacles) == 0
+}
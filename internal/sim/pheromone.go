@@ -0,0 +1,190 @@
+package sim
+
+import "math"
+
+// PheromoneKind is a scent entities lay for their teammates.
+type PheromoneKind int
+
+const (
+	FoodTrail       PheromoneKind = iota // Food was found nearby
+	DangerPheromone                      // A teammate fled from here
+	RallyPheromone                       // A teammate needs help here
+	pheromoneKinds
+)
+
+// pheromoneCellSize is the edge length of a pheromone field cell.
+const pheromoneCellSize = 10.0
+
+const (
+	defaultPheromoneEvaporation = 0.2  // Fraction lost per second
+	defaultPheromoneDiffusion   = 2.0  // Rate cells even out with their neighbours, per second
+	pheromoneDepositRate        = 10   // Deposited per second by the default brain
+	minScentGradient            = 1e-3 // Weaker gradients are treated as flat
+)
+
+// pheromoneField holds one scalar layer per team and kind over a grid
+// covering the arena. Deposits are applied while decisions are resolved and
+// the field diffuses and evaporates at the end of each tick, so it does not
+// change while entities decide.
+type pheromoneField struct {
+	cols, rows int
+	layers     [][]float64 // Indexed by team*pheromoneKinds + kind
+	scratch    []float64
+	wrap       bool // Scent spreads across the edges
+}
+
+func (f *pheromoneField) reset(teams int, width, height float64, wrap bool) {
+	f.wrap = wrap
+	f.cols = int(math.Max(1, math.Ceil(width/pheromoneCellSize)))
+	f.rows = int(math.Max(1, math.Ceil(height/pheromoneCellSize)))
+	f.layers = make([][]float64, teams*int(pheromoneKinds))
+	for i := range f.layers {
+		f.layers[i] = make([]float64, f.cols*f.rows)
+	}
+	f.scratch = make([]float64, f.cols*f.rows)
+}
+
+func (f *pheromoneField) layer(team int, kind PheromoneKind) []float64 {
+	i := team*int(pheromoneKinds) + int(kind)
+	if i < 0 || i >= len(f.layers) {
+		return nil
+	}
+	return f.layers[i]
+}
+
+// cellOf returns the cell containing a point, clamped into the field.
+func (f *pheromoneField) cellOf(x, y float64) (int, int) {
+	cx := int(clamp(math.Floor(x/pheromoneCellSize), 0, float64(f.cols-1)))
+	cy := int(clamp(math.Floor(y/pheromoneCellSize), 0, float64(f.rows-1)))
+	return cx, cy
+}
+
+func (f *pheromoneField) deposit(team int, kind PheromoneKind, x, y, amount float64) {
+	if layer := f.layer(team, kind); layer != nil {
+		cx, cy := f.cellOf(x, y)
+		layer[cy*f.cols+cx] += amount
+	}
+}
+
+// at returns the strength of a layer at a cell. Edges reflect, so a cell
+// beyond one reads the same as the nearest cell inside it, unless the field
+// wraps.
+func (f *pheromoneField) at(layer []float64, cx, cy int) float64 {
+	if f.wrap {
+		cx = (cx%f.cols + f.cols) % f.cols
+		cy = (cy%f.rows + f.rows) % f.rows
+	} else {
+		cx = min(max(cx, 0), f.cols-1)
+		cy = min(max(cy, 0), f.rows-1)
+	}
+	return layer[cy*f.cols+cx]
+}
+
+// update diffuses every layer towards the average of each cell's four
+// neighbours, then evaporates it.
+func (f *pheromoneField) update(dt, evaporation, diffusion float64) {
+	spread := math.Min(1, diffusion*dt)
+	keep := math.Exp(-evaporation * dt)
+	for _, layer := range f.layers {
+		for cy := 0; cy < f.rows; cy++ {
+			for cx := 0; cx < f.cols; cx++ {
+				i := cy*f.cols + cx
+				v := layer[i]
+				// Edges reflect, so nothing diffuses out of the arena
+				left := f.at(layer, cx-1, cy)
+				right := f.at(layer, cx+1, cy)
+				up := f.at(layer, cx, cy-1)
+				down := f.at(layer, cx, cy+1)
+				average := (left + right + up + down) / 4
+				f.scratch[i] = (v + spread*(average-v)) * keep
+			}
+		}
+		copy(layer, f.scratch)
+	}
+}
+
+// updatePheromones runs the field's diffusion and evaporation for a tick.
+func (w *World) updatePheromones(dt float64) {
+	if len(w.pheromones.layers) > 0 {
+		w.pheromones.update(dt,
+			orDefault(w.config.PheromoneEvaporation, defaultPheromoneEvaporation),
+			orDefault(w.config.PheromoneDiffusion, defaultPheromoneDiffusion))
+	}
+}
+
+// depositPheromone lays the scent a decision asked for.
+func (w *World) depositPheromone(e *Entity, d Decision, dt float64) {
+	if d.DepositRate > 0 {
+		w.pheromones.deposit(e.TeamID, d.Deposit, e.X, e.Y, d.DepositRate*dt)
+	}
+}
+
+// Scent returns the strength of Self's team's pheromone where Self is.
+func (p *Perception) Scent(kind PheromoneKind) float64 {
+	f := &p.view.world.pheromones
+	layer := f.layer(p.Self.TeamID, kind)
+	if layer == nil {
+		return 0
+	}
+	cx, cy := f.cellOf(p.Self.X, p.Self.Y)
+	return f.at(layer, cx, cy)
+}
+
+// ScentGradient returns the direction in which Self's team's pheromone
+// gets stronger fastest, scaled like the other steering behaviours, or
+// zero where the field is flat.
+func (p *Perception) ScentGradient(kind PheromoneKind) (float64, float64) {
+	f := &p.view.world.pheromones
+	layer := f.layer(p.Self.TeamID, kind)
+	if layer == nil {
+		return 0, 0
+	}
+	cx, cy := f.cellOf(p.Self.X, p.Self.Y)
+	gx := f.at(layer, cx+1, cy) - f.at(layer, cx-1, cy)
+	gy := f.at(layer, cx, cy+1) - f.at(layer, cx, cy-1)
+	length := math.Sqrt(gx*gx + gy*gy)
+	if length < minScentGradient {
+		return 0, 0
+	}
+	return gx / length * 0.1, gy / length * 0.1
+}
+
+// Heatmap is a downsampled snapshot of a pheromone, summed over teams.
+// Values holds Cols*Rows cells, row by row.
+type Heatmap struct {
+	Cols, Rows int
+	CellSize   float64
+	Values     []float64
+}
+
+// PheromoneHeatmap samples a pheromone at cellSize resolution, adding up
+// every team's layer. It returns nil when the run has no pheromones.
+func (w *World) PheromoneHeatmap(kind PheromoneKind, cellSize float64) *Heatmap {
+	f := &w.pheromones
+	if len(f.layers) == 0 {
+		return nil
+	}
+	factor := int(math.Max(1, math.Round(cellSize/pheromoneCellSize)))
+	h := &Heatmap{
+		Cols:     (f.cols + factor - 1) / factor,
+		Rows:     (f.rows + factor - 1) / factor,
+		CellSize: float64(factor) * pheromoneCellSize,
+	}
+	h.Values = make([]float64, h.Cols*h.Rows)
+	for team := 0; team < w.teams; team++ {
+		layer := f.layer(team, kind)
+		for cy := 0; cy < f.rows; cy++ {
+			for cx := 0; cx < f.cols; cx++ {
+				h.Values[(cy/factor)*h.Cols+cx/factor] += layer[cy*f.cols+cx]
+			}
+		}
+	}
+	// Report the mean of the cells each sample covers, which is fewer than
+	// factor*factor along the right and bottom edges
+	for i := range h.Values {
+		cols := min(factor, f.cols-(i%h.Cols)*factor)
+		rows := min(factor, f.rows-(i/h.Cols)*factor)
+		h.Values[i] /= float64(cols * rows)
+	}
+	return h
+}
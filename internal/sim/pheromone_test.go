@@ -0,0 +1,33 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPheromoneHeatmapAveragesPartialBlocks(t *testing.T) {
+	c := DefaultConfig()
+	c.Seed = 1
+	c.Pheromones = true
+	// 105 by 61 field cells, so the last column and row of 4 by 4 samples
+	// each cover a single cell
+	w := NewWorld(c, 1050, 610)
+	w.SetLogOutput(nil)
+	w.InitializeEntities(2, 2)
+	for team := 0; team < 2; team++ {
+		layer := w.pheromones.layer(team, FoodTrail)
+		for i := range layer {
+			layer[i] = 0.5
+		}
+	}
+
+	h := w.PheromoneHeatmap(FoodTrail, 4*pheromoneCellSize)
+	if h.Cols != 27 || h.Rows != 16 {
+		t.Fatalf("heatmap is %d by %d, want 27 by 16", h.Cols, h.Rows)
+	}
+	for i, v := range h.Values {
+		if math.Abs(v-1) > 1e-9 {
+			t.Errorf("sample %d, %d = %v, want 1", i%h.Cols, i/h.Cols, v)
+		}
+	}
+}
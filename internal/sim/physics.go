@@ -0,0 +1,129 @@
+package sim
+
+import "math"
+
+// With Config.Collisions set, entities are solid circles of radius Width.
+// After every entity has moved, overlapping pairs are pushed apart in
+// proportion to their mass and exchange an impulse along the line between
+// them, and the arena's edges push back the same way.
+
+const (
+	collisionPasses     = 2    // Contact passes per tick, to settle crowds
+	collisionSlop       = 0.01 // Overlap left alone to avoid jitter
+	collisionCorrection = 0.8  // Fraction of the overlap corrected per pass
+	contactReach        = 1.0  // Extra reach for attacks on a touching enemy
+)
+
+// mass grows with an entity's area.
+func (e *Entity) mass() float64 {
+	return e.Width * e.Width
+}
+
+// collideWalls keeps the entity's circle inside the arena, bouncing it off
+// the edges with the configured restitution.
+func (e *Entity) collideWalls(canvasWidth, canvasHeight float64) {
+	restitution := e.world.config.Restitution
+	r := math.Min(e.Width, math.Min(canvasWidth, canvasHeight)/2)
+	if e.X < r {
+		e.X = r
+		if e.VX < 0 {
+			e.VX = -e.VX * restitution
+		}
+	} else if e.X > canvasWidth-r {
+		e.X = canvasWidth - r
+		if e.VX > 0 {
+			e.VX = -e.VX * restitution
+		}
+	}
+	if e.Y < r {
+		e.Y = r
+		if e.VY < 0 {
+			e.VY = -e.VY * restitution
+		}
+	} else if e.Y > canvasHeight-r {
+		e.Y = canvasHeight - r
+		if e.VY > 0 {
+			e.VY = -e.VY * restitution
+		}
+	}
+}
+
+// collide separates overlapping entities. The spatial grid finds the
+// candidate pairs, and each pair is resolved once per pass in slice order
+// so the outcome does not depend on the number of CPUs.
+func (w *World) collide() {
+	if !w.config.Collisions {
+		return
+	}
+	for pass := 0; pass < collisionPasses; pass++ {
+		w.bodyGrid.reset(w.canvasWidth, w.canvasHeight, gridCellSize, w.config.Wrap)
+		maxRadius := 0.0
+		for i, e := range w.entities {
+			if e.Active {
+				w.bodyGrid.insert(i, e.X, e.Y)
+				maxRadius = math.Max(maxRadius, e.Width)
+			}
+		}
+
+		for i, a := range w.entities {
+			if !a.Active {
+				continue
+			}
+			w.contacts = w.bodyGrid.within(a.X, a.Y, a.Width+maxRadius, w.contacts[:0])
+			for _, j := range w.contacts {
+				if j > i {
+					w.entities[i].collideWith(w.entities[j])
+				}
+			}
+		}
+
+		for _, e := range w.entities {
+			if !e.Active {
+				continue
+			}
+			if w.config.Wrap {
+				e.wrapAround(w.canvasWidth, w.canvasHeight)
+			} else {
+				e.collideWalls(w.canvasWidth, w.canvasHeight)
+			}
+			e.resolveObstacles()
+		}
+	}
+}
+
+// collideWith resolves contact between two entities.
+func (a *Entity) collideWith(b *Entity) {
+	bx, by := a.world.unwrap(a.X, a.Y, b.X, b.Y)
+	dx := bx - a.X
+	dy := by - a.Y
+	d := math.Sqrt(dx*dx + dy*dy)
+	overlap := a.Width + b.Width - d
+	if overlap <= 0 {
+		return
+	}
+	nx, ny := 1.0, 0.0
+	if d > 0 {
+		nx, ny = dx/d, dy/d
+	}
+
+	inverseA, inverseB := 1/a.mass(), 1/b.mass()
+	inverseTotal := inverseA + inverseB
+
+	// Push them apart, the lighter one further
+	correction := math.Max(overlap-collisionSlop, 0) / inverseTotal * collisionCorrection
+	a.X -= correction * inverseA * nx
+	a.Y -= correction * inverseA * ny
+	b.X += correction * inverseB * nx
+	b.Y += correction * inverseB * ny
+
+	// Exchange momentum if they are closing on each other
+	closing := (b.VX-a.VX)*nx + (b.VY-a.VY)*ny
+	if closing >= 0 {
+		return
+	}
+	impulse := -(1 + a.world.config.Restitution) * closing / inverseTotal
+	a.VX -= impulse * inverseA * nx
+	a.VY -= impulse * inverseA * ny
+	b.VX += impulse * inverseB * nx
+	b.VY += impulse * inverseB * ny
+}
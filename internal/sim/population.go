@@ -1,87 +1,190 @@
 package sim
 
-import (
-	"math/rand"
-	"time"
-)
-
-var entities []*Entity
-var foods []*Food
-
-var config Config
+import "math"
 
 type Config struct {
 	MinSize, StartMaxSize, MaxSize, BaseSpeed float64
+	// Seed for the world's random source. Zero picks a seed from the clock.
+	Seed int64
+	// TickRate is the number of fixed simulation ticks per simulated second.
+	// Zero uses 60.
+	TickRate float64
+	// MaxCatchUpTicks caps the ticks run per Advance call. Zero uses 5.
+	MaxCatchUpTicks int
+	// FleeRadius is the distance larger enemies are noticed and fled from.
+	// Zero uses 100.
+	FleeRadius float64
+	// ViewAngle is the width in degrees of the cone entities see in,
+	// centred on their heading, and ViewDistance how far they see. Zero
+	// uses all round vision and unlimited distance.
+	ViewAngle, ViewDistance float64
+	// MemorySpan is the seconds entities remember where they saw food,
+	// enemies and injured teammates. Zero gives them no memory.
+	MemorySpan float64
+
+	// Pheromones gives each team scent layers its members lay and follow.
+	// Scents spread to neighbouring cells at PheromoneDiffusion per second
+	// and fade by PheromoneEvaporation per second. Zero values use 2 and
+	// 0.2.
+	Pheromones           bool
+	PheromoneDiffusion   float64
+	PheromoneEvaporation float64
+
+	// Communication lets teammates send each other messages. A message
+	// reaches teammates within MessageRange of the sender after
+	// MessageLatency seconds, and each entity can send at most one every
+	// MessageInterval seconds. Zero values use 300, 0.25 and 1.
+	Communication   bool
+	MessageRange    float64
+	MessageLatency  float64
+	MessageInterval float64
+
+	// PathBudget caps the path searches run each tick across every
+	// entity, spread over the population by ID. Zero uses 50.
+	PathBudget int
+
+	// Wrap makes the arena a torus: entities leaving one edge come back in
+	// at the opposite one, and distances take the shortest way round.
+	Wrap bool
+
+	// Collisions makes entities solid circles that push each other apart
+	// and bounce off the arena's edges. Restitution is how much of their
+	// closing speed they keep when they hit, from 0 to 1.
+	Collisions  bool
+	Restitution float64
+
+	// Kinematics treats steering as a desired acceleration. A size 10
+	// entity at full effort accelerates at Acceleration pixels per second
+	// squared, heavier ones more slowly. Zero uses 20. Drag is the fraction
+	// of velocity lost per second, zero for none, and TurnRate the most an
+	// entity's heading can turn in degrees per second, zero for no limit.
+	// Negative values use 0.5 and 360, as DefaultConfig does.
+	Kinematics   bool
+	Acceleration float64
+	Drag         float64
+	TurnRate     float64
+
+	// Flocking holds each team's flocking, indexed by team. Teams beyond
+	// the end, or with a zero value, do not flock.
+	Flocking []Flocking
+
+	// Reproduction lets entities with enough health give birth to an
+	// offspring on their own team.
+	Reproduction bool
+	// ReproductionHealth is the health an entity needs to give birth.
+	// Zero uses 150.
+	ReproductionHealth float64
+	// ReproductionCost is the health a birth moves from the parent to the
+	// offspring. Zero uses 75.
+	ReproductionCost float64
+	// ReproductionCooldown is the seconds an entity waits between births.
+	// Zero uses 10.
+	ReproductionCooldown float64
+	// MutationRate is the chance each trait of an offspring mutates and
+	// MutationScale the size of a mutation as a fraction of the trait's
+	// range. Zero uses 0.2 and 0.05.
+	MutationRate, MutationScale float64
+
+	// Metabolism. Entities burn BasalMetabolism energy per unit of width
+	// per second just to live, plus MovementCost per unit of speed per
+	// second, plus AttackCost each time they attack. Food restores
+	// FoodEnergy per unit of food size, up to MaxEnergy. Out of energy,
+	// entities lose StarvationDamage health per second. Zero MaxEnergy and
+	// FoodEnergy use 100 and 5. The costs and StarvationDamage can be zero
+	// to switch them off, and negative values use 0.05, 0.02, 2 and 5, as
+	// DefaultConfig does.
+	MaxEnergy        float64
+	BasalMetabolism  float64
+	MovementCost     float64
+	AttackCost       float64
+	FoodEnergy       float64
+	StarvationDamage float64
 }
 
-func InitializeEntities(population int, teams int, canvasWidth float64, canvasHeight float64) {
-	rand.Seed(time.Now().UnixNano()) // Seed the random number generator
+// DefaultConfig returns the settings the server starts with. It spells out
+// the defaults of settings where zero means none rather than the default.
+func DefaultConfig() Config {
+	return Config{
+		MinSize:      5,
+		StartMaxSize: 10,
+		MaxSize:      15,
+		BaseSpeed:    10,
+		Drag:         defaultDrag,
+		TurnRate:     defaultTurnRate,
 
-	entities = make([]*Entity, population) // Create a slice to hold the entities
-	var teamCounter = 0
-	for i := 0; i < population; i++ {
-		entities[i] = &Entity{
-			ID:          i + 1,
-			X:           randFloat(0, canvasWidth),                      // Random X position between 0 and 800
-			Y:           randFloat(0, canvasHeight),                     // Random Y position between 0 and 600
-			VX:          randFloat(-10, 10),                             // Random velocity X between -2 and 2
-			VY:          randFloat(-10, 10),                             // Random velocity Y between -2 and 2
-			Width:       randFloat(config.MinSize, config.StartMaxSize), // Random width between 20 and 100
-			Active:      true,
-			Health:      100, // Set initial health to 100
-			MaxHealth:   100,
-			TeamID:      teamCounter % teams,
-			HungerLevel: 100,
-		}
-		teamCounter = teamCounter + 1
+		BasalMetabolism:  defaultBasalMetabolism,
+		MovementCost:     defaultMovementCost,
+		AttackCost:       defaultAttackCost,
+		StarvationDamage: defaultStarvationDamage,
 	}
 }
 
-// Helper function to generate a random float64 between min and max
-func randFloat(min, max float64) float64 {
-	return min + rand.Float64()*(max-min)
+func (c Config) fleeRadius() float64 {
+	if c.FleeRadius <= 0 {
+		return defaultFleeRadius
+	}
+	return c.FleeRadius
 }
 
-var respawnTimer float64
-
-func UpdateSimulation(deltaTime float64) {
-	for i := range entities {
-		if entities[i].Active {
-			// Evaluate team needs to update the entity's priority
-			entities[i].EvaluateTeamNeed(entities)
-			// Decide on the action (assist teammate, seek food, etc.)
-			entities[i].DecideAction(entities, foods)
-			// Consume food if possible
-			entities[i].ConsumeFood(foods)
-			// Update position, perform other actions, and keep within the canvas
-			entities[i].Act(entities, canvasWidth, canvasHeight, deltaTime)
-		}
-	}
-	// Periodically respawn food items with a certain chance
-	RespawnFood(0.001, canvasWidth, canvasHeight)
-	if respawnTimer >= 5.0 {
-		RespawnFood(0.01, canvasWidth, canvasHeight)
-		respawnTimer = 0.0
+func (w *World) InitializeEntities(population int, teams int) {
+	w.reset(teams)
+
+	w.entities = make([]*Entity, population) // Create a slice to hold the entities
+	var teamCounter = 0
+	for i := 0; i < population; i++ {
+		w.entities[i] = w.newEntity(i+1, teamCounter%teams, w.DefaultGenome())
+		teamCounter = teamCounter + 1
 	}
+	w.nextID = population + 1
 }
 
-func GetEntities() []*Entity {
-	return entities
-}
+// InitializeEntitiesFromGenomes starts a run with one entity per genome,
+// where genomes[t] holds the genomes of team t.
+func (w *World) InitializeEntitiesFromGenomes(genomes [][]Genome) {
+	w.reset(len(genomes))
 
-func GetFood() []*Food {
-	return foods
+	w.entities = w.entities[:0]
+	for team, teamGenomes := range genomes {
+		for _, g := range teamGenomes {
+			w.entities = append(w.entities, w.newEntity(len(w.entities)+1, team, g))
+		}
+	}
+	w.nextID = len(w.entities) + 1
 }
 
-var (
-	canvasWidth, canvasHeight float64
-)
-
-func SetCanvas(w float64, h float64) {
-	canvasWidth = w
-	canvasHeight = h
+// reset clears the per-run state ahead of a new population.
+func (w *World) reset(teams int) {
+	w.reseed() // Every run starts from the configured seed
+	w.ticks = 0
+	w.accumulator = 0
+	w.teams = teams
+	w.stats = newStats(teams)
+	w.messages = nil
+	w.pheromones = pheromoneField{}
+	if w.config.Pheromones {
+		w.pheromones.reset(teams, w.canvasWidth, w.canvasHeight, w.config.Wrap)
+	}
 }
 
-func SetConfig(c Config) {
-	config = c
+func (w *World) newEntity(id, team int, g Genome) *Entity {
+	x, y := w.openPoint(g.BirthSize) // Random position clear of obstacles
+	vx := w.randFloat(-10, 10)       // Random velocity X between -2 and 2
+	vy := w.randFloat(-10, 10)       // Random velocity Y between -2 and 2
+	return &Entity{
+		ID:          id,
+		X:           x,
+		Y:           y,
+		VX:          vx,
+		VY:          vy,
+		Heading:     math.Atan2(vy, vx),
+		Width:       g.BirthSize,
+		Active:      true,
+		Health:      100, // Set initial health to 100
+		MaxHealth:   100,
+		TeamID:      team,
+		Energy:      w.config.startEnergy(),
+		HungerLevel: 50, // Matches starting half full of energy
+		Genome:      g,
+		world:       w,
+	}
 }
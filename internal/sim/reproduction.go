@@ -0,0 +1,99 @@
+package sim
+
+import "math"
+
+const (
+	defaultReproductionHealth   = 150.0
+	defaultReproductionCost     = 75.0
+	defaultReproductionCooldown = 10.0
+	defaultMutationRate         = 0.2
+	defaultMutationScale        = 0.05
+)
+
+func orDefault(v, fallback float64) float64 {
+	if v <= 0 {
+		return fallback
+	}
+	return v
+}
+
+// orDefaultIfNegative is orDefault for settings where zero means none.
+func orDefaultIfNegative(v, fallback float64) float64 {
+	if v < 0 {
+		return fallback
+	}
+	return v
+}
+
+// reproduce gives birth to an offspring of e once e has enough health and
+// its cooldown has run out. The offspring inherits e's team and a mutated
+// copy of its genome, and takes the cost of the birth from e's health.
+func (w *World) reproduce(e *Entity, deltaTime float64) {
+	if e.BirthCooldown > 0 {
+		e.BirthCooldown -= deltaTime
+		return
+	}
+	if !e.Active || e.Health < orDefault(w.config.ReproductionHealth, defaultReproductionHealth) {
+		return
+	}
+
+	cost := orDefault(w.config.ReproductionCost, defaultReproductionCost)
+	e.Health -= cost
+	e.BirthCooldown = orDefault(w.config.ReproductionCooldown, defaultReproductionCooldown)
+
+	g := Mutate(e.Genome, w.config,
+		orDefault(w.config.MutationRate, defaultMutationRate),
+		orDefault(w.config.MutationScale, defaultMutationScale),
+		w.rng)
+
+	x, y, angle := w.birthPlace(e, g.BirthSize)
+	child := &Entity{
+		ID:          w.nextID,
+		X:           x,
+		Y:           y,
+		VX:          math.Cos(angle) * 0.5 * w.config.BaseSpeed,
+		VY:          math.Sin(angle) * 0.5 * w.config.BaseSpeed,
+		Heading:     angle,
+		Width:       g.BirthSize,
+		Active:      true,
+		Health:      cost,
+		MaxHealth:   100,
+		TeamID:      e.TeamID,
+		Energy:      w.config.startEnergy(),
+		HungerLevel: 50, // Matches starting half full of energy
+		Genome:      g,
+		ParentID:    e.ID,
+		Generation:  e.Generation + 1,
+		// Newborns have to grow up before they can give birth themselves
+		BirthCooldown: orDefault(w.config.ReproductionCooldown, defaultReproductionCooldown),
+		world:         w,
+		bornAt:        w.ticks,
+	}
+	w.nextID++
+	w.entities = append(w.entities, child)
+	w.stats.Births[e.TeamID]++
+
+	w.logf("Entity %d (Team %d) gave birth to Entity %d.\n", e.ID, e.TeamID, child.ID)
+}
+
+// birthPlace picks where an offspring of the given size appears: just
+// beside its parent in a random direction, clear of obstacles, and wrapped
+// round or kept inside the arena. An offspring with no clear spot beside
+// its parent is born on top of it.
+func (w *World) birthPlace(parent *Entity, size float64) (x, y, angle float64) {
+	offset := parent.Width + size
+	for tries := 0; tries < 8; tries++ {
+		angle = w.randFloat(0, 2*math.Pi)
+		x = parent.X + math.Cos(angle)*offset
+		y = parent.Y + math.Sin(angle)*offset
+		if w.config.Wrap {
+			x, y = wrapCoordinate(x, w.canvasWidth), wrapCoordinate(y, w.canvasHeight)
+		} else {
+			x, y = clamp(x, 0, w.canvasWidth), clamp(y, 0, w.canvasHeight)
+		}
+		if !w.insideObstacle(x, y, size) {
+			return x, y, angle
+		}
+	}
+	return parent.X, parent.Y, angle
+}
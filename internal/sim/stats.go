@@ -0,0 +1,110 @@
+package sim
+
+// Stats holds running totals for a run, indexed by team.
+type Stats struct {
+	FoodEaten []int // Food items eaten by each team
+	Kills     []int // Enemies each team has reduced to zero health
+	Births    []int // Offspring born on each team
+}
+
+func newStats(teams int) Stats {
+	return Stats{
+		FoodEaten: make([]int, teams),
+		Kills:     make([]int, teams),
+		Births:    make([]int, teams),
+	}
+}
+
+// Summary describes the state of a run at a point in time.
+type Summary struct {
+	Seed      int64
+	Ticks     int
+	Winner    int   // Last team standing, or -1 if more or fewer than one remain
+	Survivors []int // Active entities per team
+	FoodEaten []int
+	Kills     []int
+	Births    []int
+	// Mean ticks each team's entities stayed alive, counting survivors up
+	// to the current tick
+	MeanSurvivalTicks []float64
+}
+
+// Survivors counts the active entities on each team.
+func (w *World) Survivors() []int {
+	survivors := make([]int, w.teams)
+	for _, e := range w.entities {
+		if e.Active {
+			survivors[e.TeamID]++
+		}
+	}
+	return survivors
+}
+
+// TeamsRemaining counts the teams that still have an active entity.
+func (w *World) TeamsRemaining() int {
+	remaining := 0
+	for _, count := range w.Survivors() {
+		if count > 0 {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// Winner returns the only team with active entities left, or -1 if the
+// match is still contested or everyone is dead.
+func (w *World) Winner() int {
+	if w.TeamsRemaining() != 1 {
+		return -1
+	}
+	for team, count := range w.Survivors() {
+		if count > 0 {
+			return team
+		}
+	}
+	return -1
+}
+
+// Summary reports the outcome of the run so far.
+func (w *World) Summary() Summary {
+	return Summary{
+		Seed:      w.seed,
+		Ticks:     w.ticks,
+		Winner:    w.Winner(),
+		Survivors: w.Survivors(),
+		FoodEaten: append([]int(nil), w.stats.FoodEaten...),
+		Kills:     append([]int(nil), w.stats.Kills...),
+		Births:    append([]int(nil), w.stats.Births...),
+
+		MeanSurvivalTicks: w.meanSurvivalTicks(),
+	}
+}
+
+func (w *World) meanSurvivalTicks() []float64 {
+	total := make([]float64, w.teams)
+	count := make([]int, w.teams)
+	for _, e := range w.entities {
+		total[e.TeamID] += float64(e.SurvivalTicks())
+		count[e.TeamID]++
+	}
+	for team := range total {
+		if count[team] > 0 {
+			total[team] /= float64(count[team])
+		}
+	}
+	return total
+}
+
+// Run steps the world until a single team remains or maxTicks ticks have
+// been run, whichever comes first. A maxTicks of zero or less runs until
+// the match is decided. Single team worlds run until everyone is dead.
+func (w *World) Run(maxTicks int) Summary {
+	for maxTicks <= 0 || w.ticks < maxTicks {
+		remaining := w.TeamsRemaining()
+		if remaining == 0 || (remaining == 1 && w.teams > 1) {
+			break
+		}
+		w.Step()
+	}
+	return w.Summary()
+}
@@ -0,0 +1,55 @@
+package sim
+
+import "math"
+
+// With Config.Wrap set the arena is a torus: whatever leaves one edge comes
+// back in at the opposite one, and distances and directions take the
+// shortest way round. Obstacles and planned paths do not cross the edges.
+
+// unwrap returns the copy of (x, y) closest to (fromX, fromY), which is the
+// point itself unless the arena wraps. Subtracting from it gives the
+// shortest way from one point to the other.
+func (w *World) unwrap(fromX, fromY, x, y float64) (float64, float64) {
+	if !w.config.Wrap {
+		return x, y
+	}
+	return fromX + wrapDelta(x-fromX, w.canvasWidth), fromY + wrapDelta(y-fromY, w.canvasHeight)
+}
+
+// distance between two points, the shortest way round.
+func (w *World) distance(x1, y1, x2, y2 float64) float64 {
+	x2, y2 = w.unwrap(x1, y1, x2, y2)
+	return distance(x1, y1, x2, y2)
+}
+
+// wrapDelta shortens a difference along an axis of the given size to the
+// way round that is at most half the axis.
+func wrapDelta(d, size float64) float64 {
+	if size <= 0 {
+		return d
+	}
+	return d - size*math.Round(d/size)
+}
+
+// wrapCoordinate brings a coordinate back onto an axis of the given size.
+func wrapCoordinate(v, size float64) float64 {
+	if size <= 0 {
+		return v
+	}
+	v = math.Mod(v, size)
+	if v < 0 {
+		v += size
+	}
+	if v >= size {
+		// A tiny negative remainder can round up to size itself
+		v = 0
+	}
+	return v
+}
+
+// wrapAround brings an entity that crossed an edge back in at the opposite
+// one.
+func (e *Entity) wrapAround(canvasWidth, canvasHeight float64) {
+	e.X = wrapCoordinate(e.X, canvasWidth)
+	e.Y = wrapCoordinate(e.Y, canvasHeight)
+}
@@ -0,0 +1,132 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWrapDelta(t *testing.T) {
+	tests := []struct {
+		d, size, want float64
+	}{
+		{0, 1000, 0},
+		{100, 1000, 100},
+		{-100, 1000, -100},
+		{996, 1000, -4},
+		{-996, 1000, 4},
+		{2100, 1000, 100},
+		{100, 0, 100}, // No axis to wrap round
+	}
+	for _, test := range tests {
+		if got := wrapDelta(test.d, test.size); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("wrapDelta(%v, %v) = %v, want %v", test.d, test.size, got, test.want)
+		}
+	}
+}
+
+func TestWrapCoordinate(t *testing.T) {
+	tests := []struct {
+		v, size, want float64
+	}{
+		{0, 1000, 0},
+		{500, 1000, 500},
+		{1000, 1000, 0},
+		{1004, 1000, 4},
+		{-4, 1000, 996},
+		{-2004, 1000, 996},
+		{-1e-14, 1000, 0}, // Rounds up to the size itself
+		{-4, 0, -4},
+	}
+	for _, test := range tests {
+		got := wrapCoordinate(test.v, test.size)
+		if math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("wrapCoordinate(%v, %v) = %v, want %v", test.v, test.size, got, test.want)
+		}
+		if test.size > 0 && (got < 0 || got >= test.size) {
+			t.Errorf("wrapCoordinate(%v, %v) = %v, off the axis", test.v, test.size, got)
+		}
+	}
+}
+
+// edgeWorld returns a world with two entities of different teams facing
+// each other across the left and right edges, 4 apart the short way round
+// when wrap is set.
+func edgeWorld(wrap bool) (w *World, big, small *Entity) {
+	c := DefaultConfig()
+	c.Seed = 1
+	c.Wrap = wrap
+	w = NewWorld(c, 1000, 600)
+	w.SetLogOutput(nil)
+	w.InitializeEntities(2, 2)
+	big, small = w.GetEntities()[0], w.GetEntities()[1]
+	big.X, big.Y, big.Width, big.Health, big.Invulnerable = 998, 300, 10, 100, false
+	small.X, small.Y, small.Width, small.Health, small.Invulnerable = 2, 300, 5, 100, false
+	return w, big, small
+}
+
+func TestDistanceToAcrossTheEdge(t *testing.T) {
+	_, big, small := edgeWorld(true)
+	if d := big.DistanceTo(small); math.Abs(d-4) > 1e-9 {
+		t.Errorf("wrapped distance = %v, want 4", d)
+	}
+	if d := small.DistanceTo(big); math.Abs(d-4) > 1e-9 {
+		t.Errorf("wrapped distance back = %v, want 4", d)
+	}
+
+	_, big, small = edgeWorld(false)
+	if d := big.DistanceTo(small); math.Abs(d-996) > 1e-9 {
+		t.Errorf("distance without wrap = %v, want 996", d)
+	}
+}
+
+func TestConsumeAcrossTheEdge(t *testing.T) {
+	_, big, small := edgeWorld(true)
+	big.Consume([]*Entity{big, small})
+	if small.Health >= 100 {
+		t.Error("an entity could not eat a smaller one across the edge")
+	}
+
+	_, big, small = edgeWorld(false)
+	big.Consume([]*Entity{big, small})
+	if small.Health != 100 {
+		t.Error("an entity ate a smaller one on the far side of a walled arena")
+	}
+}
+
+func TestSeekFoodAcrossTheEdge(t *testing.T) {
+	foods := func() []*Food {
+		return []*Food{
+			{ID: 1, X: 900, Y: 300, Size: 3, Active: true},
+			{ID: 2, X: 10, Y: 300, Size: 3, Active: true},
+		}
+	}
+
+	// Food 2 is 18 away round the edge and food 1 is 98 away inside
+	_, big, _ := edgeWorld(true)
+	big.X = 992
+	if vx, vy := big.SeekFood(foods()); vx <= 0 || math.Abs(vy) > 1e-9 {
+		t.Errorf("wrapped entity heads (%v, %v) for food, want right across the edge", vx, vy)
+	}
+
+	_, big, _ = edgeWorld(false)
+	big.X = 992
+	if vx, _ := big.SeekFood(foods()); vx >= 0 {
+		t.Errorf("walled entity heads %v for food, want left to the nearer food", vx)
+	}
+}
+
+func TestConsumeFoodAcrossTheEdge(t *testing.T) {
+	_, big, _ := edgeWorld(true)
+	food := &Food{ID: 1, X: 3, Y: 300, Size: 3, Active: true}
+	big.ConsumeFood([]*Food{food})
+	if food.Active {
+		t.Error("an entity could not eat food across the edge")
+	}
+
+	_, big, _ = edgeWorld(false)
+	food = &Food{ID: 1, X: 3, Y: 300, Size: 3, Active: true}
+	big.ConsumeFood([]*Food{food})
+	if !food.Active {
+		t.Error("an entity ate food on the far side of a walled arena")
+	}
+}
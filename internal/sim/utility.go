@@ -0,0 +1,303 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+)
+
+const WanderState State = "Wander"
+
+// Curve maps a normalised input in [0, 1] to a score in [0, 1].
+//
+//	linear, polynomial: Slope * (x - XShift)^Exponent + YShift
+//	logistic:           1 / (1 + e^(-Slope * (x - XShift))) + YShift
+//
+// Linear is polynomial with an exponent of one.
+type Curve struct {
+	Kind                            string
+	Slope, Exponent, XShift, YShift float64
+}
+
+func (c Curve) Eval(x float64) float64 {
+	x = clamp(x, 0, 1)
+	var y float64
+	switch c.Kind {
+	case "logistic":
+		y = 1/(1+math.Exp(-c.Slope*(x-c.XShift))) + c.YShift
+	case "polynomial":
+		y = c.Slope*math.Pow(x-c.XShift, c.Exponent) + c.YShift
+	default:
+		y = c.Slope*(x-c.XShift) + c.YShift
+	}
+	if math.IsNaN(y) {
+		return 0
+	}
+	return clamp(y, 0, 1)
+}
+
+// Consideration scores one input of an action through a curve. Inputs are:
+//
+//	hunger           HungerLevel / 100
+//	health           Health / MaxHealth
+//	team_need        TeamNeed / 100
+//	threat_distance  distance to the nearest stronger enemy / SenseRadius
+//	prey_distance    distance to the nearest weaker enemy / SenseRadius
+//	food_distance    distance to the nearest food / SenseRadius
+//
+// Distances are 1 when nothing is within SenseRadius.
+type Consideration struct {
+	Input string
+	Curve Curve
+}
+
+// UtilityAction is a candidate action, scored as Weight times the product
+// of its considerations. Actions are seek_food, assist, hunt, flee and
+// wander.
+type UtilityAction struct {
+	Action         string
+	Weight         float64
+	Considerations []Consideration
+}
+
+// UtilityConfig tunes a UtilityBrain.
+type UtilityConfig struct {
+	Actions     []UtilityAction
+	SenseRadius float64 // Distance threats, prey and food are noticed from
+	// Hysteresis is added to the score of the action chosen last tick so
+	// entities do not flicker between near-equal options.
+	Hysteresis float64
+}
+
+// DefaultUtilityConfig approximates the thresholds of the default brain
+// with smooth curves and adds fleeing and wandering.
+func DefaultUtilityConfig() UtilityConfig {
+	return UtilityConfig{
+		SenseRadius: 200,
+		Hysteresis:  0.1,
+		Actions: []UtilityAction{
+			{Action: "seek_food", Weight: 1, Considerations: []Consideration{
+				{Input: "hunger", Curve: Curve{Kind: "logistic", Slope: 20, XShift: 0.8}},
+			}},
+			{Action: "assist", Weight: 0.9, Considerations: []Consideration{
+				{Input: "team_need", Curve: Curve{Kind: "logistic", Slope: 15, XShift: 0.5}},
+			}},
+			{Action: "hunt", Weight: 0.6, Considerations: []Consideration{
+				{Input: "prey_distance", Curve: Curve{Kind: "linear", Slope: -0.5, XShift: 0, YShift: 1}},
+				{Input: "health", Curve: Curve{Kind: "linear", Slope: 1}},
+			}},
+			{Action: "flee", Weight: 1, Considerations: []Consideration{
+				{Input: "threat_distance", Curve: Curve{Kind: "polynomial", Slope: 1, Exponent: 2, XShift: 1}},
+				{Input: "health", Curve: Curve{Kind: "linear", Slope: -0.5, YShift: 1}},
+			}},
+			{Action: "wander", Weight: 0.1},
+		},
+	}
+}
+
+// LoadUtilityConfig reads a UtilityConfig from a JSON file. A file without
+// Actions uses the default actions, and one without SenseRadius or
+// Hysteresis the default values of those. Actions in the file replace the
+// defaults entirely.
+func LoadUtilityConfig(path string) (UtilityConfig, error) {
+	c := DefaultUtilityConfig()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return c, err
+	}
+	var file struct {
+		Actions     []UtilityAction
+		SenseRadius *float64
+		Hysteresis  *float64
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return c, fmt.Errorf("parsing utility config %s: %w", path, err)
+	}
+	if file.Actions != nil {
+		c.Actions = file.Actions
+	}
+	if file.SenseRadius != nil {
+		c.SenseRadius = *file.SenseRadius
+	}
+	if file.Hysteresis != nil {
+		c.Hysteresis = *file.Hysteresis
+	}
+	if c.SenseRadius <= 0 {
+		return c, fmt.Errorf("utility config %s: SenseRadius must be positive, got %g", path, c.SenseRadius)
+	}
+
+	for _, a := range c.Actions {
+		if _, ok := utilityStates[a.Action]; !ok {
+			return c, fmt.Errorf("utility config %s: unknown action %q", path, a.Action)
+		}
+		for _, con := range a.Considerations {
+			if !utilityInputNames[con.Input] {
+				return c, fmt.Errorf("utility config %s: action %q: unknown input %q", path, a.Action, con.Input)
+			}
+			if !curveKinds[con.Curve.Kind] {
+				return c, fmt.Errorf("utility config %s: action %q: unknown curve kind %q", path, a.Action, con.Curve.Kind)
+			}
+			// A fractional power of a negative number is not a number
+			if cv := con.Curve; cv.Kind == "polynomial" && cv.XShift > 0 && cv.Exponent != math.Trunc(cv.Exponent) {
+				return c, fmt.Errorf("utility config %s: action %q: polynomial with XShift %g needs a whole Exponent, got %g", path, a.Action, cv.XShift, cv.Exponent)
+			}
+		}
+	}
+	return c, nil
+}
+
+// utilityInputNames are the inputs a Consideration can score.
+var utilityInputNames = map[string]bool{
+	"hunger":          true,
+	"health":          true,
+	"team_need":       true,
+	"threat_distance": true,
+	"prey_distance":   true,
+	"food_distance":   true,
+}
+
+// curveKinds are the kinds of Curve. An empty kind is linear.
+var curveKinds = map[string]bool{
+	"":           true,
+	"linear":     true,
+	"polynomial": true,
+	"logistic":   true,
+}
+
+var utilityStates = map[string]State{
+	"seek_food": SeekFoodState,
+	"assist":    AssistingTeamMemberState,
+	"hunt":      SeekWeakerEnemyState,
+	"flee":      FleeState,
+	"wander":    WanderState,
+}
+
+// UtilityBrain scores every candidate action from response curves and picks
+// the best.
+type UtilityBrain struct {
+	Config UtilityConfig
+}
+
+func NewUtilityBrain(c UtilityConfig) *UtilityBrain {
+	return &UtilityBrain{Config: c}
+}
+
+// utilityInputs holds what a UtilityBrain perceived this tick.
+type utilityInputs struct {
+	values   map[string]float64
+	threat   *Entity
+	prey     *Entity
+	food     *Food
+	injured  *Entity // Nearest injured teammate within team need range
+	teamNeed float64
+}
+
+func (b *UtilityBrain) perceive(p *Perception) utilityInputs {
+	e := p.Self
+	radius := b.Config.SenseRadius
+	in := utilityInputs{
+		teamNeed: e.EvaluateTeamNeed(p.InjuredWithin(teamNeedRange)),
+	}
+
+	minDistance := math.Inf(1)
+	for _, other := range p.InjuredWithin(teamNeedRange) {
+		if other.TeamID == e.TeamID && other != e {
+			if d := e.DistanceTo(other); d < minDistance {
+				minDistance = d
+				in.injured = other
+			}
+		}
+	}
+
+	threatDistance, preyDistance := 1.0, 1.0
+	for _, other := range p.EntitiesWithin(radius) {
+		if !other.Active || other.TeamID == e.TeamID {
+			continue
+		}
+		d := e.DistanceTo(other) / radius
+		if other.Width > e.Width && d < threatDistance {
+			threatDistance, in.threat = d, other
+		} else if other.Width < e.Width && d < preyDistance {
+			preyDistance, in.prey = d, other
+		}
+	}
+	// Prey further than the sense radius is still worth hunting
+	if in.prey == nil {
+		in.prey = p.NearestEntity(e.isWeakerEnemy)
+	}
+
+	foodDistance := 1.0
+	if in.food = p.NearestFood(); in.food != nil {
+		foodDistance = math.Min(1, e.world.distance(e.X, e.Y, in.food.X, in.food.Y)/radius)
+	}
+
+	in.values = map[string]float64{
+		"hunger":          e.HungerLevel / 100,
+		"health":          e.Health / math.Max(e.MaxHealth, 1),
+		"team_need":       in.teamNeed / 100,
+		"threat_distance": threatDistance,
+		"prey_distance":   preyDistance,
+		"food_distance":   foodDistance,
+	}
+	return in
+}
+
+// available reports whether an action has anything to act on.
+func (in utilityInputs) available(action string) bool {
+	switch action {
+	case "seek_food":
+		return in.food != nil
+	case "assist":
+		return in.injured != nil
+	case "hunt":
+		return in.prey != nil
+	case "flee":
+		return in.threat != nil
+	}
+	return true
+}
+
+func (b *UtilityBrain) Decide(p *Perception) Decision {
+	e := p.Self
+	in := b.perceive(p)
+
+	best, bestScore := "", -1.0
+	for _, a := range b.Config.Actions {
+		if !in.available(a.Action) {
+			continue
+		}
+		score := a.Weight
+		for _, c := range a.Considerations {
+			score *= c.Curve.Eval(in.values[c.Input])
+		}
+		if utilityStates[a.Action] == e.State {
+			score += b.Config.Hysteresis
+		}
+		if score > bestScore {
+			best, bestScore = a.Action, score
+		}
+	}
+
+	d := Decision{TeamNeed: in.teamNeed, State: utilityStates[best]}
+	switch best {
+	case "seek_food":
+		d.SteerX, d.SteerY = e.SeekFood([]*Food{in.food})
+	case "assist":
+		if e.DistanceTo(in.injured) < assistRange && e.TeamAssistTimeout <= 0 {
+			d.Assist = in.injured
+		} else {
+			d.SteerX, d.SteerY = e.steerTowards(in.injured.X, in.injured.Y)
+		}
+	case "hunt":
+		d.SteerX, d.SteerY = e.SeekWeakerEnemy([]*Entity{in.prey})
+	case "flee":
+		width, height := p.Bounds()
+		d.SteerX, d.SteerY, d.FleeUrgency = e.Sense([]*Entity{in.threat}, b.Config.SenseRadius, width, height)
+	case "wander":
+		d.SteerX, d.SteerY = e.wander(p.Tick())
+	default:
+		d.State = e.State // Nothing scored, carry on as before
+	}
+	return d
+}
@@ -0,0 +1,71 @@
+package sim
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLoadUtilityConfig(t *testing.T) {
+	defaults := DefaultUtilityConfig()
+
+	c, err := LoadUtilityConfig(writeTestFile(t, "empty.json", `{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Actions) != len(defaults.Actions) || c.SenseRadius != defaults.SenseRadius || c.Hysteresis != defaults.Hysteresis {
+		t.Errorf("an empty file loaded %+v, want the defaults", c)
+	}
+
+	c, err = LoadUtilityConfig(writeTestFile(t, "actions.json", `{
+		"SenseRadius": 120,
+		"Hysteresis": 0,
+		"Actions": [{"Action": "wander", "Weight": 0.5, "Considerations": [
+			{"Input": "health", "Curve": {"Slope": 1}}
+		]}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Actions) != 1 || c.Actions[0].Action != "wander" || len(c.Actions[0].Considerations) != 1 {
+		t.Errorf("actions in the file were merged with the defaults: %+v", c.Actions)
+	}
+	if c.SenseRadius != 120 || c.Hysteresis != 0 {
+		t.Errorf("loaded SenseRadius %g and Hysteresis %g, want 120 and 0", c.SenseRadius, c.Hysteresis)
+	}
+
+	for name, content := range map[string]string{
+		"not json":           `{`,
+		"zero sense radius":  `{"SenseRadius": 0}`,
+		"unknown action":     `{"Actions": [{"Action": "dance"}]}`,
+		"unknown input":      `{"Actions": [{"Action": "hunt", "Considerations": [{"Input": "mood"}]}]}`,
+		"unknown curve":      `{"Actions": [{"Action": "hunt", "Considerations": [{"Input": "health", "Curve": {"Kind": "sine"}}]}]}`,
+		"fractional power":   `{"Actions": [{"Action": "flee", "Considerations": [{"Input": "health", "Curve": {"Kind": "polynomial", "Slope": 1, "Exponent": 0.5, "XShift": 0.5}}]}]}`,
+		"negative radius":    `{"SenseRadius": -10}`,
+		"wrongly typed data": `{"Actions": {"Action": "hunt"}}`,
+	} {
+		if _, err := LoadUtilityConfig(writeTestFile(t, "config.json", content)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestCurveEval(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve Curve
+		x     float64
+		want  float64
+	}{
+		{"linear", Curve{Slope: 1}, 0.25, 0.25},
+		{"linear clamps input", Curve{Kind: "linear", Slope: 1}, 2, 1},
+		{"linear clamps output", Curve{Kind: "linear", Slope: -1}, 0.5, 0},
+		{"polynomial", Curve{Kind: "polynomial", Slope: 1, Exponent: 2, XShift: 1}, 0.5, 0.25},
+		{"logistic midpoint", Curve{Kind: "logistic", Slope: 10, XShift: 0.5}, 0.5, 0.5},
+		{"not a number", Curve{Kind: "polynomial", Slope: 1, Exponent: 0.5, XShift: 1}, 0.5, 0},
+	}
+	for _, test := range tests {
+		if got := test.curve.Eval(test.x); math.Abs(got-test.want) > 1e-9 {
+			t.Errorf("%s: Eval(%v) = %v, want %v", test.name, test.x, got, test.want)
+		}
+	}
+}
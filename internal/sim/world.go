@@ -0,0 +1,173 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// World owns the state of a single simulation: its entities, food, config,
+// bounds and random source. Worlds are independent of each other, so a
+// process can host as many as it likes.
+type World struct {
+	entities []*Entity
+	foods    []*Food
+
+	config Config
+
+	canvasWidth, canvasHeight float64
+
+	respawnTimer float64
+
+	ticks       int     // Fixed ticks run since the last initialisation
+	accumulator float64 // Wall-clock time not yet consumed by a tick
+
+	rng  *rand.Rand
+	seed int64 // Seed the random source was last reset with
+
+	teams  int   // Number of teams the population was split into
+	nextID int   // ID the next entity born will get
+	stats  Stats // Running totals for the current run
+
+	log io.Writer // Destination for event logging, nil to discard
+
+	// Spatial index over the current tick's positions
+	entityGrid, injuredGrid, foodGrid spatialGrid
+
+	views     []view     // One per decision worker
+	decisions []Decision // Indexed like entities
+
+	teamBrains map[int]Brain
+
+	pheromones pheromoneField
+	messages   []Message // Sent between teammates but not yet delivered
+
+	leaders   []*Entity // Formation leader of each team
+	teamSizes []int     // Active members of each team
+
+	obstacles []Obstacle
+
+	// Pathfinding around the obstacles
+	nav            *navGrid
+	navDirty       bool      // Obstacles or arena changed since nav was built
+	replanInterval int       // Ticks between path searches for each entity
+	searches       sync.Pool // Of *pathSearch
+
+	// Collision broad phase over positions after everyone has moved
+	bodyGrid spatialGrid
+	contacts []int
+}
+
+// NewWorld creates an empty world with the given config and bounds.
+func NewWorld(c Config, canvasWidth, canvasHeight float64) *World {
+	w := &World{
+		config:       c,
+		canvasWidth:  canvasWidth,
+		canvasHeight: canvasHeight,
+		log:          os.Stdout,
+	}
+	w.reseed()
+	return w
+}
+
+// reseed resets the random source from the configured seed. All random
+// decisions in the world draw from this source, so two worlds with the same
+// seed and config produce identical runs.
+func (w *World) reseed() {
+	w.seed = w.config.Seed
+	if w.seed == 0 {
+		w.seed = time.Now().UnixNano()
+	}
+	w.rng = rand.New(rand.NewSource(w.seed))
+}
+
+// Seed returns the seed the current run was started with, so runs started
+// without an explicit seed can still be reproduced.
+func (w *World) Seed() int64 {
+	return w.seed
+}
+
+// UpdateSimulation runs one tick in two phases. First every active entity
+// decides what to do, in parallel, against the state the tick started with.
+// Then the decisions are applied one entity at a time in slice order, which
+// keeps the outcome the same whatever the number of CPUs.
+func (w *World) UpdateSimulation(deltaTime float64) {
+	w.rebuildIndex()
+	w.prepareNav()
+	w.deliverMessages()
+	w.assignFormations()
+	w.decide()
+
+	v := &w.views[0]
+	for i, e := range w.entities {
+		if e.Active {
+			e.ApplyDecision(w.decisions[i])
+			w.depositPheromone(e, w.decisions[i], deltaTime)
+			w.send(e, w.decisions[i].Send)
+			// Consume food if possible
+			e.ConsumeFood(v.foodWithin(e.X, e.Y, e.foodReach()))
+			// Update position, perform other actions, and keep within the canvas.
+			// The search is widened by this tick's movement as Act moves before
+			// it consumes.
+			reach := e.consumeReach() + math.Hypot(e.VX, e.VY)*deltaTime
+			e.Act(v.entitiesWithin(e.X, e.Y, reach), w.canvasWidth, w.canvasHeight, deltaTime)
+			// Give birth if healthy enough. Newborns are appended to the
+			// entities and first act next tick.
+			if w.config.Reproduction {
+				w.reproduce(e, deltaTime)
+			}
+		}
+	}
+	w.collide()
+	w.updatePheromones(deltaTime)
+
+	// Periodically respawn food items with a certain chance
+	w.RespawnFood(0.001)
+	if w.respawnTimer >= 5.0 {
+		w.RespawnFood(0.01)
+		w.respawnTimer = 0.0
+	}
+}
+
+func (w *World) GetEntities() []*Entity {
+	return w.entities
+}
+
+func (w *World) GetFood() []*Food {
+	return w.foods
+}
+
+func (w *World) SetCanvas(width float64, height float64) {
+	w.canvasWidth = width
+	w.canvasHeight = height
+	w.navDirty = true
+}
+
+func (w *World) SetConfig(c Config) {
+	w.config = c
+}
+
+func (w *World) Config() Config {
+	return w.config
+}
+
+// Helper function to generate a random float64 between min and max
+func (w *World) randFloat(min, max float64) float64 {
+	return min + w.rng.Float64()*(max-min)
+}
+
+// SetLogOutput sets where the world writes its event log. Passing nil
+// silences it, which headless runs want.
+func (w *World) SetLogOutput(out io.Writer) {
+	w.log = out
+}
+
+func (w *World) logf(format string, args ...any) {
+	if w.log != nil {
+		fmt.Fprintf(w.log, format, args...)
+	}
+}
@@ -0,0 +1,110 @@
+package sim
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+// runWorld builds a world, runs it for ticks fixed steps and returns a
+// snapshot of everything observable about it afterwards.
+func runWorld(t *testing.T, c Config, population, teams, ticks int, setup func(w *World)) []byte {
+	t.Helper()
+	w := NewWorld(c, 1000, 600)
+	w.SetLogOutput(nil)
+	if setup != nil {
+		setup(w)
+	}
+	w.InitializeEntities(population, teams)
+	w.InitializeFood(200)
+	for i := 0; i < ticks; i++ {
+		w.Step()
+	}
+
+	snapshot, err := json.Marshal(struct {
+		Entities []*Entity
+		Foods    []*Food
+		Summary  Summary
+	}{w.GetEntities(), w.GetFood(), w.Summary()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return snapshot
+}
+
+func testConfig(seed int64) Config {
+	c := DefaultConfig()
+	c.Seed = seed
+	return c
+}
+
+func TestSameSeedSameState(t *testing.T) {
+	first := runWorld(t, testConfig(7), 200, 3, 600, nil)
+	second := runWorld(t, testConfig(7), 200, 3, 600, nil)
+	if !bytes.Equal(first, second) {
+		t.Fatal("two runs with the same seed and config ended in different states")
+	}
+
+	other := runWorld(t, testConfig(8), 200, 3, 600, nil)
+	if bytes.Equal(first, other) {
+		t.Fatal("runs with different seeds ended in the same state")
+	}
+}
+
+// TestSameStateWhateverTheWorkers runs enough entities to split decisions
+// across several workers, with every feature that decides or resolves
+// switched on, and checks the outcome does not depend on GOMAXPROCS.
+func TestSameStateWhateverTheWorkers(t *testing.T) {
+	for _, wrap := range []bool{false, true} {
+		c := testConfig(11)
+		c.ViewAngle, c.ViewDistance = 220, 250
+		c.MemorySpan = 5
+		c.Pheromones = true
+		c.Communication = true
+		c.Flocking = []Flocking{{}, {Separation: 2, Alignment: 1, Cohesion: 1}, {Formation: "wedge"}}
+		c.Collisions = true
+		c.Kinematics = true
+		c.Reproduction = true
+		c.Wrap = wrap
+
+		setup := func(w *World) {
+			err := w.SetObstacles([]Obstacle{
+				{Shape: "circle", X: 300, Y: 300, Radius: 40},
+				{Shape: "box", X: 600, Y: 100, Width: 40, Height: 200},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.SetTeamBrain(0, NewUtilityBrain(DefaultUtilityConfig()))
+			w.SetTeamBrain(1, NewBehaviourTreeBrain(DefaultBehaviourTree()))
+			w.SetTeamBrain(2, NewNeuralBrain(NewNetwork([]int{NeuralInputs, 8, NeuralOutputs}, rand.New(rand.NewSource(1)))))
+		}
+
+		var snapshots [][]byte
+		for _, procs := range []int{1, 8} {
+			previous := runtime.GOMAXPROCS(procs)
+			snapshots = append(snapshots, runWorld(t, c, 520, 4, 120, setup))
+			runtime.GOMAXPROCS(previous)
+		}
+		if !bytes.Equal(snapshots[0], snapshots[1]) {
+			t.Errorf("wrap=%v: runs with GOMAXPROCS 1 and 8 ended in different states", wrap)
+		}
+	}
+}
+
+// BenchmarkUpdateSimulation measures one tick at the 10,000 entity and
+// 5,000 food scale the spatial grid is meant to hold 60 ticks a second at.
+func BenchmarkUpdateSimulation(b *testing.B) {
+	w := NewWorld(testConfig(1), 4000, 2400)
+	w.SetLogOutput(nil)
+	w.InitializeEntities(10000, 4)
+	w.InitializeFood(5000)
+	delta := w.TickDuration()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		w.UpdateSimulation(delta)
+	}
+}
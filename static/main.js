@@ -65,8 +65,15 @@ socket.onclose = () => {
 window.addEventListener('resize', function() { resizeCanvas(socket) });
 
 // Handle incoming messages from the WebSocket
+let obstacles = [];
+
 socket.onmessage = (event) => {
     const data = JSON.parse(event.data);
+    // The map arrives once, before any state
+    if (data.Obstacles !== undefined) {
+        obstacles = data.Obstacles || [];
+        return;
+    }
     updateCanvas(data);
 };
 
@@ -92,6 +99,16 @@ function updateCanvas(data) {
     // Clear the canvas
     ctx.clearRect(0, 0, canvas.width, canvas.height);
 
+    // Draw the pheromone heatmaps under everything else
+    if (data.Heatmaps) {
+        drawHeatmap(data.Heatmaps.food, [0, 200, 0]);
+        drawHeatmap(data.Heatmaps.danger, [220, 0, 0]);
+        drawHeatmap(data.Heatmaps.rally, [0, 80, 255]);
+    }
+
+    // Obstacles go under the rest of the scene
+    drawObstacles();
+
     const activeColor = '#000000';
     const inactiveColor = '#D3D3D3';
     const invulnColor = '#0000FF';
@@ -153,6 +170,12 @@ function updateCanvas(data) {
             ctx.fill(); // Fill the diamond
         });
     }
+
+    // Draw view cones under the entities when the server sends them
+    if (data.View) {
+        activeEntities.forEach((entity) => drawViewCone(entity, data.View));
+    }
+
     // Then draw active entities
     activeEntities.forEach((entity) => {
         ctx.beginPath(); // Start a new path
@@ -186,6 +209,58 @@ function updateCanvas(data) {
 
 }
 
+function drawObstacles() {
+    ctx.fillStyle = '#555555';
+    obstacles.forEach((obstacle) => {
+        ctx.beginPath();
+        if (obstacle.Shape === 'circle') {
+            ctx.arc(obstacle.X, obstacle.Y, obstacle.Radius, 0, Math.PI * 2);
+        } else if (obstacle.Shape === 'box') {
+            ctx.rect(obstacle.X, obstacle.Y, obstacle.Width, obstacle.Height);
+        } else {
+            obstacle.Points.forEach((point, i) => {
+                if (i === 0) {
+                    ctx.moveTo(point.X, point.Y);
+                } else {
+                    ctx.lineTo(point.X, point.Y);
+                }
+            });
+            ctx.closePath();
+        }
+        ctx.fill();
+    });
+}
+
+function drawHeatmap(heatmap, rgb) {
+    if (!heatmap) {
+        return;
+    }
+    for (let row = 0; row < heatmap.Rows; row++) {
+        for (let col = 0; col < heatmap.Cols; col++) {
+            const value = heatmap.Values[row * heatmap.Cols + col];
+            if (value < 0.01) {
+                continue;
+            }
+            // Saturate so faint trails still show
+            const alpha = Math.min(0.5, value / 2);
+            ctx.fillStyle = `rgba(${rgb[0]}, ${rgb[1]}, ${rgb[2]}, ${alpha})`;
+            ctx.fillRect(col * heatmap.CellSize, row * heatmap.CellSize, heatmap.CellSize, heatmap.CellSize);
+        }
+    }
+}
+
+function drawViewCone(entity, view) {
+    // Unlimited vision is drawn out to the edge of the canvas
+    const distance = view.Distance > 0 ? view.Distance : Math.hypot(canvas.width, canvas.height);
+    const halfAngle = view.Angle > 0 && view.Angle < 360 ? view.Angle * Math.PI / 360 : Math.PI;
+    ctx.beginPath();
+    ctx.moveTo(entity.X, entity.Y);
+    ctx.arc(entity.X, entity.Y, distance, entity.Heading - halfAngle, entity.Heading + halfAngle);
+    ctx.closePath();
+    ctx.fillStyle = 'rgba(255, 255, 0, 0.08)';
+    ctx.fill();
+}
+
 function getTeamColor(teamID, totalTeams, isInvulnerable=false, isInactive=false) {
     // Scale the hue based on the team ID
     let hue = (360 / totalTeams) * teamID; // Evenly distribute hues across 360 degrees
@@ -244,6 +319,26 @@ function saveSimulationSettings() {
     const startMaxSize = document.getElementById('StartMaxSize').value;
     const maxSize = document.getElementById('MaxSize').value;
     const baseSpeed = document.getElementById('BaseSpeed').value;
+    const seed = document.getElementById('Seed').value;
+    const tickRate = document.getElementById('TickRate').value;
+    const fleeRadius = document.getElementById('FleeRadius').value;
+    const viewAngle = document.getElementById('ViewAngle').value;
+    const viewDistance = document.getElementById('ViewDistance').value;
+    const memorySpan = document.getElementById('MemorySpan').value;
+    const pheromones = document.getElementById('Pheromones').checked;
+    const communication = document.getElementById('Communication').checked;
+    const wrap = document.getElementById('Wrap').checked;
+    const collisions = document.getElementById('Collisions').checked;
+    const restitution = document.getElementById('Restitution').value;
+    const kinematics = document.getElementById('Kinematics').checked;
+    const acceleration = document.getElementById('Acceleration').value;
+    const drag = document.getElementById('Drag').value;
+    const turnRate = document.getElementById('TurnRate').value;
+    const reproduction = document.getElementById('Reproduction').checked;
+    const metabolism = {};
+    ['MaxEnergy', 'BasalMetabolism', 'MovementCost', 'AttackCost', 'FoodEnergy', 'StarvationDamage'].forEach((name) => {
+        metabolism[name] = Number(document.getElementById(name).value);
+    });
 
     // Example of handling the settings
     //
@@ -256,6 +351,23 @@ function saveSimulationSettings() {
         StartMaxSize: Number(startMaxSize),
         MaxSize: Number(maxSize),
         baseSpeed: Number(baseSpeed),
+        Seed: Number(seed),
+        TickRate: Number(tickRate),
+        FleeRadius: Number(fleeRadius),
+        ViewAngle: Number(viewAngle),
+        ViewDistance: Number(viewDistance),
+        MemorySpan: Number(memorySpan),
+        Pheromones: pheromones,
+        Communication: communication,
+        Wrap: wrap,
+        Collisions: collisions,
+        Restitution: Number(restitution),
+        Kinematics: kinematics,
+        Acceleration: Number(acceleration),
+        Drag: Number(drag),
+        TurnRate: Number(turnRate),
+        Reproduction: reproduction,
+        ...metabolism,
     }
     socket.send(JSON.stringify(data))
 